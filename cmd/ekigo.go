@@ -31,7 +31,7 @@ func main() {
 	// now test that configuration is not the default one
 	if conf.ID() == "" || conf.SubID() == "" || conf.Msg() == "" ||
 		conf.Separator() == "" || conf.PathMainDir() == "" ||
-		conf.GpsPort() == "" {
+		(conf.GpsPort() == "" && conf.GpsReplayFile() == "" && conf.GpsSource() != "gpsd") {
 		fmt.Println("Please edit the configuration file.")
 		os.Exit(1)
 	}
@@ -42,25 +42,27 @@ func main() {
 		panic(err)
 	}
 
-	// Ok, now get time from GPS and update system time
-	err = mission.Gps().Update()
+	// Ok, now get time from GPS and update system time,
+	// giving the GPS reader some time to receive data
+	for range 10 {
+		err = mission.Gps().Update()
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Second)
+	}
 	if err != nil {
 		mission.Log().Log(logging.LogError, fmt.Sprintf("Error updating GPS: %v", err))
 	}
-	h, m, s, err := mission.Gps().Hms()
+	err = mission.SetTimeGPS()
 	if err != nil {
-		mission.Log().Log(logging.LogError, fmt.Sprintf("Error getting GPS time:: %v", err))
-	} else {
-		err = mission.SetTimeGPS(h, m, s)
-		if err != nil {
-			mission.Log().Log(logging.LogError, fmt.Sprintf("Error setting system time: %v", err))
-		}
+		mission.Log().Log(logging.LogError, fmt.Sprintf("Error setting system time: %v", err))
 	}
 
 	///////// MAIN LOOP /////////
 	for {
-		// send Telemetry
-		for range conf.PacketRepeat() {
+		// send Telemetry, with the rates of the flight phase
+		for range mission.PhaseConfig().PacketRepeat {
 			// check for commands TODO
 
 			// send telemetry
@@ -74,20 +76,23 @@ func main() {
 			}
 
 			// write datalog
-			err = mission.DataLog().Log(logging.LogClean, mission.Telemetry().CsvString())
+			err = mission.WriteDataLog()
 			if err != nil {
 				mission.Log().Log(logging.LogError, fmt.Sprintf("Problem writing datalog: %v", err))
 			}
 
-			time.Sleep(time.Duration(conf.PacketDelay()) * time.Second)
+			time.Sleep(time.Duration(mission.PhaseConfig().PacketDelay) * time.Second)
 		}
 
 		// send SSDV
+		if mission.PhaseConfig().SkipSsdv {
+			continue
+		}
 		err = mission.SendSSDV(conf)
 		if err != nil {
 			mission.Log().Log(logging.LogError, fmt.Sprintf("Problem sending SSDV: %v", err))
 		}
-		time.Sleep(time.Duration(conf.PacketDelay()) * time.Second)
+		time.Sleep(time.Duration(mission.PhaseConfig().PacketDelay) * time.Second)
 	}
 
 }
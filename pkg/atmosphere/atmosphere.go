@@ -0,0 +1,124 @@
+package atmosphere
+
+import (
+	"math"
+)
+
+// International Standard Atmosphere (ISA, US Standard Atmosphere 1976)
+// up to 86 km. Altitudes are geometric (like GPS altitudes), converted
+// to geopotential for the model. Pressures in mbar (hPa).
+
+const (
+	// standard sea level pressure (mbar)
+	SeaLevelPressure = 1013.25
+	// top of the model (m)
+	MaxAltitude = 86000.0
+
+	re = 6356766.0 // earth radius for geopotential altitude (m)
+	g0 = 9.80665   // gravity (m/s^2)
+	m0 = 0.0289644 // molar mass of air (kg/mol)
+	r  = 8.31432   // gas constant (J/(mol K)), as in the standard
+)
+
+// geopotential altitude of a geometric altitude
+func geopotential(alt float64) float64 {
+	return re * alt / (re + alt)
+}
+
+// geometric altitude of a geopotential altitude
+func geometric(h float64) float64 {
+	return re * h / (re - h)
+}
+
+// layer of the atmosphere, from its base
+type layer struct {
+	alt   float64 // base geopotential altitude (m)
+	lapse float64 // temperature lapse rate (K/m)
+	temp  float64 // base temperature (K)
+	pres  float64 // base pressure (mbar)
+}
+
+var layers = []layer{
+	{0, -0.0065, 288.15, 1013.25},       // troposphere
+	{11000, 0, 216.65, 226.3206},        // tropopause
+	{20000, 0.001, 216.65, 54.74889},    // stratosphere
+	{32000, 0.0028, 228.65, 8.680187},   // stratosphere
+	{47000, 0, 270.65, 1.109063},        // stratopause
+	{51000, -0.0028, 270.65, 0.6693887}, // mesosphere
+	{71000, -0.002, 214.65, 0.0395642},  // mesosphere
+	{84852, 0, 186.946, 0.003733836},    // top
+}
+
+// layerAt returns the layer of a geopotential altitude
+func layerAt(alt float64) layer {
+	l := layers[0]
+	for _, next := range layers[1:] {
+		if alt < next.alt {
+			break
+		}
+		l = next
+	}
+	return l
+}
+
+// PressureAltitude returns the ISA altitude (m) of a pressure (mbar)
+func PressureAltitude(pres float64) float64 {
+	l := layers[0]
+	for _, next := range layers[1:] {
+		if pres > next.pres {
+			break
+		}
+		l = next
+	}
+	if l.lapse == 0 {
+		return geometric(l.alt - r*l.temp/(g0*m0)*math.Log(pres/l.pres))
+	}
+	return geometric(l.alt + l.temp/l.lapse*(math.Pow(pres/l.pres, -r*l.lapse/(g0*m0))-1))
+}
+
+// Pressure returns the ISA pressure (mbar) at an altitude (m)
+func Pressure(alt float64) float64 {
+	alt = geopotential(alt)
+	l := layerAt(alt)
+	if l.lapse == 0 {
+		return l.pres * math.Exp(-g0*m0*(alt-l.alt)/(r*l.temp))
+	}
+	return l.pres * math.Pow(l.temp/(l.temp+l.lapse*(alt-l.alt)), g0*m0/(r*l.lapse))
+}
+
+// Temperature returns the ISA temperature (C) at an altitude (m)
+func Temperature(alt float64) float64 {
+	alt = geopotential(alt)
+	l := layerAt(alt)
+	return l.temp + l.lapse*(alt-l.alt) - 273.15
+}
+
+// Density returns the ISA air density (kg/m^3) at an altitude (m)
+func Density(alt float64) float64 {
+	return Pressure(alt) * 100 * m0 / (r * (Temperature(alt) + 273.15))
+}
+
+// Atmosphere is the ISA corrected with a sea level pressure reference,
+// like an altimeter setting (QNH)
+type Atmosphere struct {
+	SeaLevel float64 // sea level pressure (mbar)
+}
+
+// Standard returns the atmosphere with the standard sea level pressure
+func Standard() Atmosphere {
+	return Atmosphere{SeaLevel: SeaLevelPressure}
+}
+
+// Calibrate sets the sea level pressure from the pressure (mbar)
+// measured at a known altitude (m), like the launch site
+func (a *Atmosphere) Calibrate(pres float64, alt float64) {
+	a.SeaLevel = pres * SeaLevelPressure / Pressure(alt)
+}
+
+// Altitude returns the altitude (m) of a pressure (mbar)
+func (a Atmosphere) Altitude(pres float64) float64 {
+	if pres <= 0 || a.SeaLevel <= 0 {
+		return math.NaN()
+	}
+	return PressureAltitude(pres * SeaLevelPressure / a.SeaLevel)
+}
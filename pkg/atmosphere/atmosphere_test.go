@@ -0,0 +1,72 @@
+package atmosphere
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPressure(t *testing.T) {
+	// US Standard Atmosphere 1976 tables, geometric altitudes
+	tests := []struct {
+		alt, pres, temp float64
+	}{
+		{0, 1013.25, 15.0},
+		{5000, 540.48, -17.5},
+		{11000, 226.99, -56.38},
+		{20000, 55.293, -56.5},
+		{30000, 11.970, -46.64},
+		{40000, 2.8714, -22.8},
+		{50000, 0.7978, -2.5},
+		{60000, 0.2196, -26.13},
+	}
+	for _, test := range tests {
+		if p := Pressure(test.alt); math.Abs(p-test.pres)/test.pres > 1e-3 {
+			t.Errorf("Pressure at %.0fm: %f, want %f", test.alt, p, test.pres)
+		}
+		if temp := Temperature(test.alt); math.Abs(temp-test.temp) > 0.1 {
+			t.Errorf("Temperature at %.0fm: %f, want %f", test.alt, temp, test.temp)
+		}
+		if alt := PressureAltitude(test.pres); math.Abs(alt-test.alt) > 5 {
+			t.Errorf("Pressure altitude of %f: %f, want %f", test.pres, alt, test.alt)
+		}
+	}
+	// round trip at every altitude
+	for alt := -500.0; alt < MaxAltitude; alt += 250 {
+		if a := PressureAltitude(Pressure(alt)); math.Abs(a-alt) > 0.01 {
+			t.Errorf("Pressure altitude round trip at %.0fm: %f", alt, a)
+		}
+	}
+}
+
+func TestDensity(t *testing.T) {
+	tests := []struct {
+		alt, density float64
+	}{
+		{0, 1.225},
+		{10000, 0.41351},
+		{30000, 0.018410},
+	}
+	for _, test := range tests {
+		if d := Density(test.alt); math.Abs(d-test.density)/test.density > 1e-3 {
+			t.Errorf("Density at %.0fm: %f, want %f", test.alt, d, test.density)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	a := Standard()
+	if alt := a.Altitude(SeaLevelPressure); alt != 0 {
+		t.Errorf("Standard sea level altitude: %f", alt)
+	}
+	// launch site at 545m, high pressure day
+	a.Calibrate(960.0, 545.0)
+	if alt := a.Altitude(960.0); math.Abs(alt-545.0) > 0.01 {
+		t.Errorf("Calibrated altitude: %f", alt)
+	}
+	if a.SeaLevel <= SeaLevelPressure {
+		t.Errorf("Bad sea level pressure: %f", a.SeaLevel)
+	}
+	if alt := a.Altitude(0); !math.IsNaN(alt) {
+		t.Errorf("Altitude of no pressure: %f", alt)
+	}
+}
@@ -7,6 +7,15 @@ import (
 	"github.com/BurntSushi/toml"
 )
 
+// PhaseConfig changes the mission behavior in a flight phase, zero
+// values keep the global configuration
+type PhaseConfig struct {
+	PacketRepeat int    `toml:"packet_repeat"`
+	PacketDelay  int    `toml:"packet_delay"`
+	LoraPwr      string `toml:"lora_pwr"` // "low" or "high"
+	SkipSsdv     bool   `toml:"skip_ssdv"`
+}
+
 type Config interface {
 	ID() string
 	SubID() string
@@ -14,11 +23,37 @@ type Config interface {
 	Separator() string
 	PacketRepeat() int
 	PacketDelay() int
+	Phases() map[string]PhaseConfig
+	TelemetryFormat() string
+	UkhasFields() []string
+	AprsFields() []string
+	CsvFields() []string
+	AprsPath() []string
+	DatalogFormat() string
+	SummaryEvery() int
+	TimeSyncThreshold() float64
+	TimeSyncInterval() int
 	BattEnablePin() uint8
 	LedPin() uint8
 	PwrPin() uint8
+	GpsSource() string
 	GpsPort() string
 	GpsSpeed() int
+	GpsAutobaud() bool
+	GpsdAddr() string
+	GpsDynModel() string
+	GpsMinSats() int
+	GpsMinFix() string
+	GpsMaxHDOP() float64
+	GpsRequireActive() bool
+	GpsMaxHSpeed() float64
+	GpsMaxVSpeed() float64
+	GpsMinAlt() float64
+	GpsMaxAlt() float64
+	GpsReplayFile() string
+	GpsReplaySpeed() float64
+	GpsReplayLoop() bool
+	PpsPin() uint8
 	LoraHighPwr() uint8
 	LoraSPIChannel() uint8
 	LoraCSPin() uint8
@@ -34,6 +69,8 @@ type Config interface {
 	TempExternalAddr() string
 	BaroI2CBus() uint8
 	BaroI2CAddr() uint16
+	BaroSeaLevel() float64
+	BaroAltFallback() bool
 	PathMainDir() string
 	PathImgDir() string
 	PathLogPrefix() string
@@ -49,12 +86,42 @@ type config struct {
 	PacketRepeat_ int    `toml:"packet_repeat"`
 	PacketDelay_  int    `toml:"packet_delay"`
 
+	Phases_ map[string]PhaseConfig `toml:"phase"`
+
+	TelemetryFormat_ string   `toml:"telemetry_format"`
+	UkhasFields_     []string `toml:"ukhas_fields"`
+	AprsFields_      []string `toml:"aprs_fields"`
+	CsvFields_       []string `toml:"csv_fields"`
+	AprsPath_        []string `toml:"aprs_path"`
+	DatalogFormat_   string   `toml:"datalog_format"`
+	SummaryEvery_    int      `toml:"summary_every"`
+
+	TimeSyncThreshold_ float64 `toml:"time_sync_threshold"`
+	TimeSyncInterval_  int     `toml:"time_sync_interval"`
+
 	BattEnablePin_ uint8 `toml:"batt_en_pin"`
 	LedPin_        uint8 `toml:"led_pin"`
 	PwrPin_        uint8 `toml:"pwr_pin"`
 
-	GpsPort_  string `toml:"gps_port"`
-	GpsSpeed_ int    `toml:"gps_speed"`
+	GpsSource_        string  `toml:"gps_source"`
+	GpsPort_          string  `toml:"gps_port"`
+	GpsSpeed_         int     `toml:"gps_speed"`
+	GpsAutobaud_      bool    `toml:"gps_autobaud"`
+	GpsdAddr_         string  `toml:"gps_gpsd_addr"`
+	GpsDynModel_      string  `toml:"gps_dyn_model"`
+	GpsMinSats_       int     `toml:"gps_min_sats"`
+	GpsMinFix_        string  `toml:"gps_min_fix"`
+	GpsMaxHDOP_       float64 `toml:"gps_max_hdop"`
+	GpsRequireActive_ bool    `toml:"gps_require_active"`
+	GpsMaxHSpeed_     float64 `toml:"gps_max_hspeed"`
+	GpsMaxVSpeed_     float64 `toml:"gps_max_vspeed"`
+	GpsMinAlt_        float64 `toml:"gps_min_alt"`
+	GpsMaxAlt_        float64 `toml:"gps_max_alt"`
+	GpsReplayFile_    string  `toml:"gps_replay_file"`
+	GpsReplaySpeed_   float64 `toml:"gps_replay_speed"`
+	GpsReplayLoop_    bool    `toml:"gps_replay_loop"`
+
+	PpsPin_ uint8 `toml:"pps_pin"`
 
 	LoraSPIChannel_ uint8   `toml:"lora_spi_channel"`
 	LoraCSPin_      uint8   `toml:"lora_cs_pin"`
@@ -72,8 +139,10 @@ type config struct {
 	TempInternalAddr_ string `toml:"temp_int_addr"`
 	TempExternalAddr_ string `toml:"temp_ext_addr"`
 
-	BaroI2CBus_  uint8  `toml:"baro_i2c_bus"`
-	BaroI2CAddr_ uint16 `toml:"baro_i2c_addr"`
+	BaroI2CBus_      uint8   `toml:"baro_i2c_bus"`
+	BaroI2CAddr_     uint16  `toml:"baro_i2c_addr"`
+	BaroSeaLevel_    float64 `toml:"baro_sea_level"`
+	BaroAltFallback_ bool    `toml:"baro_alt_fallback"`
 
 	PathMainDir_   string `toml:"path_main_dir"`
 	PathImgDir_    string `toml:"path_images_dir"`
@@ -117,34 +186,62 @@ func CreateDefaultConfig() (string, error) {
 }
 
 // getters
-func (c *config) ID() string               { return c.Id_ }
-func (c *config) SubID() string            { return c.SubId_ }
-func (c *config) Msg() string              { return c.Msg_ }
-func (c *config) Separator() string        { return c.Separator_ }
-func (c *config) PacketRepeat() int        { return c.PacketRepeat_ }
-func (c *config) PacketDelay() int         { return c.PacketDelay_ }
-func (c *config) BattEnablePin() uint8     { return c.BattEnablePin_ }
-func (c *config) LedPin() uint8            { return c.LedPin_ }
-func (c *config) PwrPin() uint8            { return c.PwrPin_ }
-func (c *config) GpsPort() string          { return c.GpsPort_ }
-func (c *config) GpsSpeed() int            { return c.GpsSpeed_ }
-func (c *config) LoraSPIChannel() uint8    { return c.LoraSPIChannel_ }
-func (c *config) LoraCSPin() uint8         { return c.LoraCSPin_ }
-func (c *config) LoraIntPin() uint8        { return c.LoraIntPin_ }
-func (c *config) LoraFreq() float64        { return c.LoraFreq_ }
-func (c *config) LoraLowPwr() uint8        { return c.LoraLowPwr_ }
-func (c *config) LoraHighPwr() uint8       { return c.LoraHighPwr_ }
-func (c *config) ADCChan() int             { return c.ADCChan_ }
-func (c *config) ADCCsPin() uint8          { return c.ADCCsPin_ }
-func (c *config) ADCVBatt() uint8          { return c.ADCVBatt_ }
-func (c *config) ADCVDivider() float64     { return c.ADCVDivider_ }
-func (c *config) ADCVMult() float64        { return c.ADCVMult_ }
-func (c *config) TempInternalAddr() string { return c.TempInternalAddr_ }
-func (c *config) TempExternalAddr() string { return c.TempExternalAddr_ }
-func (c *config) BaroI2CBus() uint8        { return c.BaroI2CBus_ }
-func (c *config) BaroI2CAddr() uint16      { return c.BaroI2CAddr_ }
-func (c *config) PathMainDir() string      { return c.PathMainDir_ }
-func (c *config) PathImgDir() string       { return c.PathImgDir_ }
-func (c *config) PathLogPrefix() string    { return c.PathLogPrefix_ }
-func (c *config) SsdvSize() string         { return c.SsdvSize_ }
-func (c *config) SsdvName() string         { return c.SsdvName_ }
+func (c *config) ID() string                     { return c.Id_ }
+func (c *config) SubID() string                  { return c.SubId_ }
+func (c *config) Msg() string                    { return c.Msg_ }
+func (c *config) Separator() string              { return c.Separator_ }
+func (c *config) PacketRepeat() int              { return c.PacketRepeat_ }
+func (c *config) PacketDelay() int               { return c.PacketDelay_ }
+func (c *config) Phases() map[string]PhaseConfig { return c.Phases_ }
+func (c *config) TelemetryFormat() string        { return c.TelemetryFormat_ }
+func (c *config) UkhasFields() []string          { return c.UkhasFields_ }
+func (c *config) AprsFields() []string           { return c.AprsFields_ }
+func (c *config) CsvFields() []string            { return c.CsvFields_ }
+func (c *config) AprsPath() []string             { return c.AprsPath_ }
+func (c *config) DatalogFormat() string          { return c.DatalogFormat_ }
+func (c *config) SummaryEvery() int              { return c.SummaryEvery_ }
+func (c *config) TimeSyncThreshold() float64     { return c.TimeSyncThreshold_ }
+func (c *config) TimeSyncInterval() int          { return c.TimeSyncInterval_ }
+func (c *config) BattEnablePin() uint8           { return c.BattEnablePin_ }
+func (c *config) LedPin() uint8                  { return c.LedPin_ }
+func (c *config) PwrPin() uint8                  { return c.PwrPin_ }
+func (c *config) GpsSource() string              { return c.GpsSource_ }
+func (c *config) GpsPort() string                { return c.GpsPort_ }
+func (c *config) GpsSpeed() int                  { return c.GpsSpeed_ }
+func (c *config) GpsAutobaud() bool              { return c.GpsAutobaud_ }
+func (c *config) GpsdAddr() string               { return c.GpsdAddr_ }
+func (c *config) GpsDynModel() string            { return c.GpsDynModel_ }
+func (c *config) GpsMinSats() int                { return c.GpsMinSats_ }
+func (c *config) GpsMinFix() string              { return c.GpsMinFix_ }
+func (c *config) GpsMaxHDOP() float64            { return c.GpsMaxHDOP_ }
+func (c *config) GpsRequireActive() bool         { return c.GpsRequireActive_ }
+func (c *config) GpsMaxHSpeed() float64          { return c.GpsMaxHSpeed_ }
+func (c *config) GpsMaxVSpeed() float64          { return c.GpsMaxVSpeed_ }
+func (c *config) GpsMinAlt() float64             { return c.GpsMinAlt_ }
+func (c *config) GpsMaxAlt() float64             { return c.GpsMaxAlt_ }
+func (c *config) GpsReplayFile() string          { return c.GpsReplayFile_ }
+func (c *config) GpsReplaySpeed() float64        { return c.GpsReplaySpeed_ }
+func (c *config) GpsReplayLoop() bool            { return c.GpsReplayLoop_ }
+func (c *config) PpsPin() uint8                  { return c.PpsPin_ }
+func (c *config) LoraSPIChannel() uint8          { return c.LoraSPIChannel_ }
+func (c *config) LoraCSPin() uint8               { return c.LoraCSPin_ }
+func (c *config) LoraIntPin() uint8              { return c.LoraIntPin_ }
+func (c *config) LoraFreq() float64              { return c.LoraFreq_ }
+func (c *config) LoraLowPwr() uint8              { return c.LoraLowPwr_ }
+func (c *config) LoraHighPwr() uint8             { return c.LoraHighPwr_ }
+func (c *config) ADCChan() int                   { return c.ADCChan_ }
+func (c *config) ADCCsPin() uint8                { return c.ADCCsPin_ }
+func (c *config) ADCVBatt() uint8                { return c.ADCVBatt_ }
+func (c *config) ADCVDivider() float64           { return c.ADCVDivider_ }
+func (c *config) ADCVMult() float64              { return c.ADCVMult_ }
+func (c *config) TempInternalAddr() string       { return c.TempInternalAddr_ }
+func (c *config) TempExternalAddr() string       { return c.TempExternalAddr_ }
+func (c *config) BaroI2CBus() uint8              { return c.BaroI2CBus_ }
+func (c *config) BaroI2CAddr() uint16            { return c.BaroI2CAddr_ }
+func (c *config) BaroSeaLevel() float64          { return c.BaroSeaLevel_ }
+func (c *config) BaroAltFallback() bool          { return c.BaroAltFallback_ }
+func (c *config) PathMainDir() string            { return c.PathMainDir_ }
+func (c *config) PathImgDir() string             { return c.PathImgDir_ }
+func (c *config) PathLogPrefix() string          { return c.PathLogPrefix_ }
+func (c *config) SsdvSize() string               { return c.SsdvSize_ }
+func (c *config) SsdvName() string               { return c.SsdvName_ }
@@ -0,0 +1,487 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+	"github.com/ladecadence/EkiGo/pkg/telemetry"
+)
+
+// Decoder for the telemetry strings generated by pkg/telemetry,
+// for ground software and log analysis
+
+const (
+	dateFormat = "02-01-2006 15:04:05"
+	aprsSymbol = "O"
+	// APRS coordinates have 0.01 minutes resolution
+	aprsCoordTolerance = 0.01 / 60.0
+	// CSV fields of all the datalogs, before the optional columns
+	csvFields = 16
+)
+
+var (
+	ErrFormat = errors.New("Bad telemetry format")
+	ErrValue  = errors.New("Bad telemetry value")
+)
+
+// Record is a decoded telemetry packet or CSV row, invalid values are NaN
+type Record struct {
+	ID        string // only in APRS strings
+	Msg       string // only in APRS strings
+	Time      time.Time
+	Pos       position.Position
+	Hdg       float64
+	Spd       float64
+	Sats      int
+	Vbat      float64
+	Baro      float64
+	Tin       float64
+	Tout      float64
+	ARate     float64
+	HighPwr   bool
+	GpsHealth string           // empty in strings without it
+	Seq       int              // 0 in strings without it
+	Uptime    time.Duration    // 0 in strings without it
+	Boot      int              // 0 in strings without it
+	Status    telemetry.Status // 0 in strings without it
+	BaroAlt   float64          // NaN in strings without it
+	FiltAlt   float64          // NaN in strings without it
+	Phase     string           // empty in strings without it
+	PredLat   float64          // NaN without landing prediction
+	PredLon   float64          // NaN without landing prediction
+}
+
+// newRecord returns a record without the optional values
+func newRecord() Record {
+	return Record{BaroAlt: math.NaN(), FiltAlt: math.NaN(), PredLat: math.NaN(), PredLon: math.NaN()}
+}
+
+func formatError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrFormat, fmt.Sprintf(format, args...))
+}
+
+func valueError(name string, value string) error {
+	return fmt.Errorf("%w: %s %q", ErrValue, name, value)
+}
+
+// parseFloat parses a float field, with an optional key like "A="
+func parseFloat(field string, key string) (float64, error) {
+	if !strings.HasPrefix(field, key) {
+		return 0, formatError("expected %s, got %q", key, field)
+	}
+	v, err := strconv.ParseFloat(field[len(key):], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, valueError(key, field)
+	}
+	return v, nil
+}
+
+// parseOptional parses an optional float field, NaN if empty
+func parseOptional(field string, key string) (float64, error) {
+	if field == key {
+		return math.NaN(), nil
+	}
+	return parseFloat(field, key)
+}
+
+// parseInt parses a positive integer field, with an optional key like "SEQ="
+func parseInt(field string, key string) (int, error) {
+	if !strings.HasPrefix(field, key) {
+		return 0, formatError("expected %s, got %q", key, field)
+	}
+	v, err := strconv.Atoi(field[len(key):])
+	if err != nil || v < 0 {
+		return 0, valueError(key, field)
+	}
+	return v, nil
+}
+
+// parseDateTime parses the DD-MM-YYYY and HH:MM:SS fields
+func parseDateTime(date string, tm string) (time.Time, error) {
+	t, err := time.Parse(dateFormat, date+" "+tm)
+	if err != nil {
+		return time.Time{}, valueError("date", date+" "+tm)
+	}
+	return t, nil
+}
+
+// parseCoord parses decimal coordinates with hemisphere, like 43.549067N,
+// NaN if empty
+func parseCoord(s string, pos string, neg string, limit float64) (float64, error) {
+	if s == "" {
+		return math.NaN(), nil
+	}
+	if len(s) < 2 {
+		return 0, valueError("coordinate", s)
+	}
+	v, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil || v < 0 || v > limit {
+		return 0, valueError("coordinate", s)
+	}
+	switch s[len(s)-1:] {
+	case pos:
+		return v, nil
+	case neg:
+		return -v, nil
+	}
+	return 0, valueError("hemisphere", s)
+}
+
+// parseAprsCoords parses APRS uncompressed coordinates, ddmm.mmN and
+// dddmm.mmW, returning decimal degrees
+func parseAprsCoords(lat string, lon string) (float64, float64, error) {
+	if len(lat) != 8 || len(lon) != 9 {
+		return 0, 0, formatError("bad APRS coordinates %q %q", lat, lon)
+	}
+	la, err := strconv.ParseFloat(lat[:7], 64)
+	if err != nil {
+		return 0, 0, valueError("APRS latitude", lat)
+	}
+	lo, err := strconv.ParseFloat(lon[:8], 64)
+	if err != nil {
+		return 0, 0, valueError("APRS longitude", lon)
+	}
+	pos := position.FromNMEA(la, lat[7:], lo, lon[8:], 0, time.Time{})
+	if (lat[7] != 'N' && lat[7] != 'S') || math.Abs(pos.Lat) > 90 {
+		return 0, 0, valueError("APRS latitude", lat)
+	}
+	if (lon[8] != 'E' && lon[8] != 'W') || math.Abs(pos.Lon) > 180 {
+		return 0, 0, valueError("APRS longitude", lon)
+	}
+	return pos.Lat, pos.Lon, nil
+}
+
+// DecodeAprs decodes a telemetry string generated by AprsString, sep is
+// the separator configured in the mission
+func DecodeAprs(s string, sep string) (Record, error) {
+	if sep == "" {
+		return Record{}, formatError("empty separator")
+	}
+	s = strings.TrimRight(s, "\r\n")
+	if !strings.HasPrefix(s, "$$") {
+		return Record{}, formatError("no $$ start")
+	}
+	id, rest, ok := strings.Cut(s[2:], "!")
+	if !ok || id == "" {
+		return Record{}, formatError("no id")
+	}
+	r := newRecord()
+	r.ID = id
+
+	// position, ddmm.mmN<sep>dddmm.mmWO
+	if len(rest) < 8+len(sep)+9+len(aprsSymbol) ||
+		rest[8:8+len(sep)] != sep || rest[8+len(sep)+9:8+len(sep)+9+len(aprsSymbol)] != aprsSymbol {
+		return Record{}, formatError("bad APRS position")
+	}
+	// blank without a fix
+	aprsLat, aprsLon := math.NaN(), math.NaN()
+	noFix := rest[:8] == telemetry.AprsNoLat && rest[8+len(sep):8+len(sep)+9] == telemetry.AprsNoLon
+	var err error
+	if !noFix {
+		aprsLat, aprsLon, err = parseAprsCoords(rest[:8], rest[8+len(sep):8+len(sep)+9])
+		if err != nil {
+			return Record{}, err
+		}
+	}
+	rest = rest[8+len(sep)+9+len(aprsSymbol):]
+
+	// hdg, spd, A, V, P, TI, TO, date, time, GPS, SATS, AR, [GH], [SEQ], [UP],
+	// [BOOT], [ST], [BA], [FA], [PH], [PLAT], [PLON], msg
+	f := strings.SplitN(rest, sep, 23)
+	if len(f) < 13 {
+		return Record{}, formatError("expected at least 13 fields, got %d", len(f))
+	}
+	if r.Hdg, err = parseOptional(f[0], ""); err != nil {
+		return Record{}, err
+	}
+	if r.Spd, err = parseOptional(f[1], ""); err != nil {
+		return Record{}, err
+	}
+	if r.Pos.Alt, err = parseOptional(f[2], "A="); err != nil {
+		return Record{}, err
+	}
+	if r.Vbat, err = parseOptional(f[3], "V="); err != nil {
+		return Record{}, err
+	}
+	if r.Baro, err = parseOptional(f[4], "P="); err != nil {
+		return Record{}, err
+	}
+	if r.Tin, err = parseOptional(f[5], "TI="); err != nil {
+		return Record{}, err
+	}
+	if r.Tout, err = parseOptional(f[6], "TO="); err != nil {
+		return Record{}, err
+	}
+	if r.Time, err = parseDateTime(f[7], f[8]); err != nil {
+		return Record{}, err
+	}
+	r.Pos.Time = r.Time
+
+	// precise coordinates, must match the APRS ones, empty if invalid
+	gps, ok := strings.CutPrefix(f[9], "GPS=")
+	lat, lon, found := strings.Cut(gps, ",")
+	if gps == "" {
+		lat, lon, found = "", "", true
+	}
+	if !ok || !found {
+		return Record{}, formatError("expected GPS=, got %q", f[9])
+	}
+	if r.Pos.Lat, err = parseCoord(lat, "N", "S", 90); err != nil {
+		return Record{}, err
+	}
+	if r.Pos.Lon, err = parseCoord(lon, "E", "W", 180); err != nil {
+		return Record{}, err
+	}
+	if noFix && gps != "" {
+		return Record{}, valueError("GPS coordinates don't match APRS position", f[9])
+	}
+	if gps != "" && (math.Abs(r.Pos.Lat-aprsLat) > aprsCoordTolerance || math.Abs(r.Pos.Lon-aprsLon) > aprsCoordTolerance) {
+		return Record{}, valueError("GPS coordinates don't match APRS position", f[9])
+	}
+
+	sats, err := parseFloat(f[10], "SATS=")
+	if err != nil {
+		return Record{}, err
+	}
+	if sats < 0 || sats != math.Trunc(sats) {
+		return Record{}, valueError("SATS=", f[10])
+	}
+	r.Sats = int(sats)
+	if r.ARate, err = parseOptional(f[11], "AR="); err != nil {
+		return Record{}, err
+	}
+
+	// GPS health, sequence, uptime, boot counter, status, barometric and
+	// filtered altitudes, flight phase and landing prediction, not in older
+	// strings
+	i := 12
+optional:
+	for ; i < len(f)-1; i++ {
+		key, value, _ := strings.Cut(f[i], "=")
+		switch key {
+		case "GH":
+			r.GpsHealth = value
+		case "SEQ":
+			if r.Seq, err = parseInt(f[i], "SEQ="); err != nil {
+				return Record{}, err
+			}
+		case "UP":
+			up, err := parseInt(f[i], "UP=")
+			if err != nil {
+				return Record{}, err
+			}
+			r.Uptime = time.Duration(up) * time.Second
+		case "BOOT":
+			if r.Boot, err = parseInt(f[i], "BOOT="); err != nil {
+				return Record{}, err
+			}
+		case "ST":
+			st, err := parseInt(f[i], "ST=")
+			if err != nil {
+				return Record{}, err
+			}
+			r.Status = telemetry.Status(st)
+		case "BA":
+			if r.BaroAlt, err = parseOptional(f[i], "BA="); err != nil {
+				return Record{}, err
+			}
+		case "FA":
+			if r.FiltAlt, err = parseOptional(f[i], "FA="); err != nil {
+				return Record{}, err
+			}
+		case "PH":
+			r.Phase = value
+		case "PLAT":
+			if r.PredLat, err = parseOptional(f[i], "PLAT="); err != nil {
+				return Record{}, err
+			}
+		case "PLON":
+			if r.PredLon, err = parseOptional(f[i], "PLON="); err != nil {
+				return Record{}, err
+			}
+		default:
+			break optional
+		}
+	}
+	msg := strings.Join(f[i:], sep)
+
+	// message and power
+	switch {
+	case strings.HasSuffix(msg, " - H"):
+		r.HighPwr = true
+	case strings.HasSuffix(msg, " - L"):
+	default:
+		return Record{}, formatError("no power mark in %q", msg)
+	}
+	r.Msg = msg[:len(msg)-len(" - H")]
+
+	return r, nil
+}
+
+// columns of the CSV datalog with the default fields and the mission
+// fields, the first csvFields are in all the datalogs
+var csvColumns = []string{"date", "time", "lat", "ns", "lon", "ew", "alt", "vbat", "tin", "tout", "baro",
+	"hdg", "spd", "sats", "arate", "pwr", "gps_health", "seq", "uptime", "boot", "status", "balt", "falt",
+	"phase", "pred_lat", "pred_lon"}
+
+// DecodeCsv decodes a CSV row generated by CsvString with the default
+// fields. The optional columns are the ones of the last datalog version,
+// use a CsvDecoder with the header for older or configured datalogs
+func DecodeCsv(s string) (Record, error) {
+	f := strings.Split(strings.TrimRight(s, "\r\n"), ",")
+	if len(f) < csvFields || len(f) > len(csvColumns) {
+		return Record{}, formatError("expected %d to %d fields, got %d", csvFields, len(csvColumns), len(f))
+	}
+	return decodeColumns(csvColumns[:len(f)], f)
+}
+
+// CsvDecoder decodes the rows of a CSV datalog by the names of its header,
+// so any datalog version or configured field list can be decoded
+type CsvDecoder struct {
+	columns []string
+}
+
+// NewCsvDecoder returns a decoder for the rows of a CSV datalog with this
+// header (the line after the schema comment). The header needs the date,
+// time and pos columns, columns of unknown fields are ignored
+func NewCsvDecoder(header string) (*CsvDecoder, error) {
+	columns := strings.Split(strings.TrimRight(header, "\r\n"), ",")
+	for i, c := range columns {
+		// without unit, like alt[m]
+		columns[i], _, _ = strings.Cut(c, "[")
+	}
+	for _, c := range csvColumns[:6] {
+		if !slices.Contains(columns, c) {
+			return nil, formatError("no %s column in the CSV header", c)
+		}
+	}
+	return &CsvDecoder{columns: columns}, nil
+}
+
+// Decode decodes a CSV datalog row
+func (d *CsvDecoder) Decode(s string) (Record, error) {
+	f := strings.Split(strings.TrimRight(s, "\r\n"), ",")
+	if len(f) != len(d.columns) {
+		return Record{}, formatError("expected %d fields, got %d", len(d.columns), len(f))
+	}
+	return decodeColumns(d.columns, f)
+}
+
+// decodeColumns decodes the fields of a CSV row with these column names
+func decodeColumns(names []string, f []string) (Record, error) {
+	row := map[string]string{}
+	for i, name := range names {
+		row[name] = f[i]
+	}
+	r := newRecord()
+	var err error
+	if r.Time, err = parseDateTime(row["date"], row["time"]); err != nil {
+		return Record{}, err
+	}
+	r.Pos.Time = r.Time
+	if r.Pos.Lat, err = parseCoord(row["lat"]+row["ns"], "N", "S", 90); err != nil {
+		return Record{}, err
+	}
+	if r.Pos.Lon, err = parseCoord(row["lon"]+row["ew"], "E", "W", 180); err != nil {
+		return Record{}, err
+	}
+	floats := map[string]*float64{"alt": &r.Pos.Alt, "vbat": &r.Vbat, "tin": &r.Tin, "tout": &r.Tout,
+		"baro": &r.Baro, "hdg": &r.Hdg, "spd": &r.Spd, "arate": &r.ARate, "balt": &r.BaroAlt,
+		"falt": &r.FiltAlt, "pred_lat": &r.PredLat, "pred_lon": &r.PredLon}
+	for i, name := range names {
+		v := f[i]
+		if p, ok := floats[name]; ok {
+			if *p, err = parseOptional(v, ""); err != nil {
+				return Record{}, err
+			}
+			continue
+		}
+		switch name {
+		case "sats":
+			if r.Sats, err = strconv.Atoi(v); err != nil || r.Sats < 0 {
+				return Record{}, valueError("sats", v)
+			}
+		case "pwr":
+			switch v {
+			case "H":
+				r.HighPwr = true
+			case "L":
+			default:
+				return Record{}, valueError("power", v)
+			}
+		case "gps_health":
+			r.GpsHealth = v
+		case "seq":
+			r.Seq, err = parseInt(v, "")
+		case "uptime":
+			var up int
+			up, err = parseInt(v, "")
+			r.Uptime = time.Duration(up) * time.Second
+		case "boot":
+			r.Boot, err = parseInt(v, "")
+		case "status":
+			var st int
+			st, err = parseInt(v, "")
+			r.Status = telemetry.Status(st)
+		case "phase":
+			r.Phase = v
+		}
+		if err != nil {
+			return Record{}, err
+		}
+	}
+	return r, nil
+}
+
+// Summary is a decoded flight summary string
+type Summary struct {
+	ID                 string
+	telemetry.Extremes // MaxAltTime only has the time of the day
+}
+
+// DecodeSummary decodes a flight summary string generated by
+// SummaryString, sep is the separator configured in the mission
+func DecodeSummary(s string, sep string) (Summary, error) {
+	if sep == "" {
+		return Summary{}, formatError("empty separator")
+	}
+	s = strings.TrimRight(s, "\r\n")
+	if !strings.HasPrefix(s, "$$") {
+		return Summary{}, formatError("no $$ start")
+	}
+	f := strings.Split(s[2:], sep)
+	id, ok := strings.CutSuffix(f[0], "!SUM")
+	if !ok || id == "" {
+		return Summary{}, formatError("not a summary string")
+	}
+	if len(f) != 8 {
+		return Summary{}, formatError("expected 8 summary fields, got %d", len(f))
+	}
+	sum := Summary{ID: id, Extremes: telemetry.NewExtremes()}
+	var err error
+	if sum.MaxAlt, err = parseOptional(f[1], "AMAX="); err != nil {
+		return Summary{}, err
+	}
+	tm, ok := strings.CutPrefix(f[2], "AMAXT=")
+	if !ok {
+		return Summary{}, formatError("expected AMAXT=, got %q", f[2])
+	}
+	if tm != "" {
+		if sum.MaxAltTime, err = time.Parse(time.TimeOnly, tm); err != nil {
+			return Summary{}, valueError("AMAXT=", f[2])
+		}
+	}
+	values := []*float64{&sum.MinTin, &sum.MinTout, &sum.MinVbat, &sum.MaxAscent, &sum.MaxDescent}
+	for i, key := range []string{"TIMIN=", "TOMIN=", "VMIN=", "ARMAX=", "DRMAX="} {
+		if *values[i], err = parseOptional(f[3+i], key); err != nil {
+			return Summary{}, err
+		}
+	}
+	return sum, nil
+}
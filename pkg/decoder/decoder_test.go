@@ -0,0 +1,248 @@
+package decoder
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+	"github.com/ladecadence/EkiGo/pkg/telemetry"
+)
+
+func testTelemetry(sep string, msg string) telemetry.Telemetry {
+	telem := telemetry.New("EA1IDZ-11", msg, sep)
+	pos := position.Position{Lat: 43.549067, Lon: -5.663050, Alt: 12345.6}
+	telem.SetBoot(4)
+	telem.Update(pos, 271.3, 12.4, 9, "OK", 4.1, 150.3, -42.5, -55.1, true)
+	telem.Set("arate", 2.5)
+	return telem
+}
+
+// testMissionTelemetry adds the fields registered by the mission, the
+// landing prediction is not set
+func testMissionTelemetry(t *testing.T, sep string, msg string) telemetry.Telemetry {
+	t.Helper()
+	telem := telemetry.New("EA1IDZ-11", msg, sep)
+	for _, f := range []telemetry.Field{
+		{Name: "balt", Key: "BA", Unit: "m", Type: telemetry.FloatField, Precision: 1},
+		{Name: "falt", Key: "FA", Unit: "m", Type: telemetry.FloatField, Precision: 1},
+		{Name: "phase", Key: "PH", Type: telemetry.StringField},
+		{Name: "pred_lat", Key: "PLAT", Unit: "deg", Type: telemetry.FloatField, Precision: 5},
+		{Name: "pred_lon", Key: "PLON", Unit: "deg", Type: telemetry.FloatField, Precision: 5},
+	} {
+		if err := telem.Register(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pos := position.Position{Lat: 43.549067, Lon: -5.663050, Alt: 12345.6}
+	telem.SetBoot(4)
+	telem.Update(pos, 271.3, 12.4, 9, "OK", 4.1, 150.3, -42.5, -55.1, true)
+	telem.Set("arate", 2.5)
+	for name, v := range map[string]any{"phase": "ascent", "balt": 12301.2, "falt": 12344.9} {
+		if err := telem.Set(name, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return telem
+}
+
+func checkRecord(t *testing.T, r Record) {
+	t.Helper()
+	if math.Abs(r.Pos.Lat-43.549067) > 1e-6 || math.Abs(r.Pos.Lon+5.663050) > 1e-6 || r.Pos.Alt != 12345.6 {
+		t.Errorf("Bad decoded position: %+v", r.Pos)
+	}
+	if r.Hdg != 271.3 || r.Spd != 12.4 || r.ARate != 2.5 || r.Sats != 9 || r.Vbat != 4.1 || r.Baro != 150.3 ||
+		r.Tin != -42.5 || r.Tout != -55.1 || !r.HighPwr || r.GpsHealth != "OK" || r.Seq != 1 || r.Boot != 4 {
+		t.Errorf("Bad decoded values: %+v", r)
+	}
+	if d := time.Since(r.Time); d < -time.Second || d > time.Second*2 {
+		t.Errorf("Bad decoded time: %v", r.Time)
+	}
+}
+
+func TestDecodeAprs(t *testing.T) {
+	for _, sep := range []string{"/", "|"} {
+		telem := testTelemetry(sep, "EkiGo test/flight\nsecond line")
+		r, err := DecodeAprs(telem.AprsString(), sep)
+		if err != nil {
+			t.Fatalf("Error decoding %q: %v", telem.AprsString(), err)
+		}
+		checkRecord(t, r)
+		if r.ID != "EA1IDZ-11" || r.Msg != "EkiGo test/flight - second line" || !math.IsNaN(r.BaroAlt) {
+			t.Errorf("Bad decoded APRS fields: %+v", r)
+		}
+
+		// with the mission fields
+		telem = testMissionTelemetry(t, sep, "EkiGo test/flight")
+		telem.Set("pred_lat", 43.12345)
+		telem.Set("pred_lon", -5.54321)
+		r, err = DecodeAprs(telem.AprsString(), sep)
+		if err != nil {
+			t.Fatalf("Error decoding %q: %v", telem.AprsString(), err)
+		}
+		checkRecord(t, r)
+		if r.BaroAlt != 12301.2 || r.FiltAlt != 12344.9 || r.Phase != "ascent" || r.Msg != "EkiGo test/flight" ||
+			r.PredLat != 43.12345 || r.PredLon != -5.54321 {
+			t.Errorf("Bad decoded mission fields: %+v", r)
+		}
+	}
+
+	// older strings without GPS health
+	r, err := DecodeAprs("$$TEST!4332.94N/00539.78WO0.0/0.0/A=545.4/V=4.1/P=1019.5/TI=15.5/TO=5.4/"+
+		"18-10-2026/06:00:18/GPS=43.549067N,005.663050W/SATS=8/AR=1.2/Test message - L\n", "/")
+	if err != nil || r.GpsHealth != "" || r.Seq != 0 || r.Msg != "Test message" || r.HighPwr || r.ARate != 1.2 {
+		t.Errorf("Bad decoded old APRS string: %+v, %v", r, err)
+	}
+}
+
+func TestDecodeAprsErrors(t *testing.T) {
+	good := testTelemetry("/", "Test").AprsString()
+	tests := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrFormat},
+		{strings.TrimPrefix(good, "$$"), ErrFormat},
+		{strings.Replace(good, "!", "", 1), ErrFormat},
+		{strings.Replace(good, "A=", "B=", 1), ErrFormat},
+		{strings.Replace(good, "V=4.1", "V=x", 1), ErrValue},
+		{strings.Replace(good, "GPS=43", "GPS=44", 1), ErrValue},
+		{strings.Replace(good, "N,005", "N,006", 1), ErrValue},
+		{strings.Replace(good, "4332.94N/00539.78W", "    .  N/     .  E", 1), ErrValue},
+		{strings.Replace(good, "SATS=9", "SATS=-1", 1), ErrValue},
+		{strings.Replace(good, " - H", "", 1), ErrFormat},
+		{good[:40], ErrFormat},
+	}
+	for i, test := range tests {
+		if _, err := DecodeAprs(test.s, "/"); !errors.Is(err, test.err) {
+			t.Errorf("%d: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestDecodeCsv(t *testing.T) {
+	telem := testTelemetry("/", "Test")
+	r, err := DecodeCsv(telem.CsvString())
+	if err != nil {
+		t.Fatalf("Error decoding %q: %v", telem.CsvString(), err)
+	}
+	checkRecord(t, r)
+
+	telem = testMissionTelemetry(t, "/", "Test")
+	r, err = DecodeCsv(telem.CsvString())
+	if err != nil {
+		t.Fatalf("Error decoding %q: %v", telem.CsvString(), err)
+	}
+	checkRecord(t, r)
+	if r.BaroAlt != 12301.2 || r.FiltAlt != 12344.9 || r.Phase != "ascent" ||
+		!math.IsNaN(r.PredLat) || !math.IsNaN(r.PredLon) {
+		t.Errorf("Bad decoded mission fields: %+v", r)
+	}
+
+	if _, err := DecodeCsv("18-10-2026,06:00:18,43.5,N"); !errors.Is(err, ErrFormat) {
+		t.Errorf("Short row decoded: %v", err)
+	}
+	bad := strings.Replace(telem.CsvString(), ",W,", ",X,", 1)
+	if _, err := DecodeCsv(bad); !errors.Is(err, ErrValue) {
+		t.Errorf("Bad hemisphere decoded: %v", err)
+	}
+}
+
+func TestCsvDecoder(t *testing.T) {
+	telem := testMissionTelemetry(t, "/", "Test")
+	d, err := NewCsvDecoder(telem.CsvHeader())
+	if err != nil {
+		t.Fatalf("Error reading header %q: %v", telem.CsvHeader(), err)
+	}
+	r, err := d.Decode(telem.CsvString())
+	if err != nil {
+		t.Fatalf("Error decoding %q: %v", telem.CsvString(), err)
+	}
+	checkRecord(t, r)
+	if r.BaroAlt != 12301.2 || r.FiltAlt != 12344.9 || r.Phase != "ascent" {
+		t.Errorf("Bad decoded mission fields: %+v", r)
+	}
+
+	// version 1 datalog, without sequence, uptime, boot and status
+	d, err = NewCsvDecoder("date,time,lat,ns,lon,ew,alt[m],vbat[V],tin[C],tout[C],baro[mbar],hdg[deg],spd[kn]," +
+		"sats,arate[m/s],pwr,gps_health,balt[m],falt[m],phase,pred_lat[deg],pred_lon[deg]")
+	if err != nil {
+		t.Fatalf("Error reading version 1 header: %v", err)
+	}
+	r, err = d.Decode("18-10-2026,06:00:18,43.549067,N,5.663050,W,12345.6,4.12,-42.5,-55.1,150.3,271.3,12.4," +
+		"9,2.5,H,OK,12301.2,12344.9,ascent,,")
+	if err != nil || r.BaroAlt != 12301.2 || r.FiltAlt != 12344.9 || r.Seq != 0 || r.Vbat != 4.12 || r.Phase != "ascent" {
+		t.Errorf("Bad decoded version 1 row: %+v, %v", r, err)
+	}
+
+	// configured fields, unknown fields ignored
+	d, err = NewCsvDecoder("time,date,pos_x,lat,ns,lon,ew,seq")
+	if err != nil {
+		t.Fatalf("Error reading configured header: %v", err)
+	}
+	r, err = d.Decode("06:00:18,18-10-2026,7,43.549067,N,5.663050,W,12")
+	if err != nil || r.Seq != 12 || r.Time.Hour() != 6 || !math.IsNaN(r.BaroAlt) {
+		t.Errorf("Bad decoded configured row: %+v, %v", r, err)
+	}
+	if _, err := d.Decode("06:00:18,18-10-2026"); !errors.Is(err, ErrFormat) {
+		t.Errorf("Short row decoded: %v", err)
+	}
+	if _, err := NewCsvDecoder("date,time,alt[m]"); !errors.Is(err, ErrFormat) {
+		t.Errorf("Header without position accepted: %v", err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	telem := testTelemetry("/", "Test")
+	pos := position.Position{Lat: 43.549067, Lon: -5.663050, Alt: 12345.6}
+	telem.Update(pos, 271.3, 12.4, 9, "NODATA", 4.1, math.NaN(), math.NaN(), -55.1, true)
+	telem.Set("arate", 2.5)
+	telem.SetValidity(telemetry.Invalid, "pos", "gps")
+	telem.SetValidity(telemetry.Stale, "alt")
+
+	invalid := func(r Record) bool {
+		return math.IsNaN(r.Pos.Lat) && math.IsNaN(r.Pos.Lon) && math.IsNaN(r.Baro) && math.IsNaN(r.Tin) &&
+			r.Pos.Alt == 12345.6 && r.Tout == -55.1 && r.Status.Validity("pos") == telemetry.Invalid &&
+			r.Status.Validity("tin") == telemetry.Invalid && r.Status.Validity("alt") == telemetry.Stale
+	}
+	r, err := DecodeAprs(telem.AprsString(), "/")
+	if err != nil || !invalid(r) {
+		t.Errorf("Bad decoded invalid APRS values %q: %+v, %v", telem.AprsString(), r, err)
+	}
+	r, err = DecodeCsv(telem.CsvString())
+	if err != nil || !invalid(r) {
+		t.Errorf("Bad decoded invalid CSV values %q: %+v, %v", telem.CsvString(), r, err)
+	}
+}
+
+func TestDecodeSummary(t *testing.T) {
+	e := telemetry.NewExtremes()
+	e.Update(time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC), 31234.5, 5, -58.2, math.NaN(), 5.8)
+	e.Update(time.Date(2026, 10, 18, 7, 1, 0, 0, time.UTC), 30000, 6, -50, math.NaN(), -35.2)
+	for _, sep := range []string{"/", "|"} {
+		s := testTelemetry(sep, "Test").SummaryString(e)
+		sum, err := DecodeSummary(s, sep)
+		if err != nil {
+			t.Fatalf("Error decoding %q: %v", s, err)
+		}
+		if sum.ID != "EA1IDZ-11" || sum.MaxAlt != 31234.5 || sum.MaxAltTime.Format(time.TimeOnly) != "07:00:00" ||
+			sum.MinTin != 5 || sum.MinTout != -58.2 || !math.IsNaN(sum.MinVbat) || sum.MaxAscent != 5.8 || sum.MaxDescent != 35.2 {
+			t.Errorf("Bad decoded summary %q: %+v", s, sum)
+		}
+	}
+
+	good := testTelemetry("/", "Test").SummaryString(e)
+	for i, bad := range []string{
+		"",
+		testTelemetry("/", "Test").AprsString(),
+		strings.Replace(good, "/DRMAX=35.2", "", 1),
+		strings.Replace(good, "TIMIN=5.0", "TIMIN=cold", 1),
+		strings.Replace(good, "AMAXT=07:00:00", "AMAXT=7h", 1),
+	} {
+		if _, err := DecodeSummary(bad, "/"); err == nil {
+			t.Errorf("%d: bad summary decoded: %q", i, bad)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ds18b20
 
 import (
 	"errors"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -14,14 +15,15 @@ type DS18B20 struct {
 
 func (ds *DS18B20) Init(dev string) {
 	ds.Device = "/sys/bus/w1/devices/" + dev + "/w1_slave"
-	ds.Temp = 999.99
+	ds.Temp = math.NaN()
 }
 
+// Read returns the temperature, NaN with an error if it can't be read
 func (ds *DS18B20) Read() (float64, error) {
 	// try to open the device
 	buf, err := os.ReadFile(ds.Device)
 	if err != nil {
-		return 999.99, err
+		return math.NaN(), err
 	}
 	// convert to string
 	data := string(buf)
@@ -29,18 +31,23 @@ func (ds *DS18B20) Read() (float64, error) {
 	// get second line
 	lines := strings.Split(data, "\n")
 	if len(lines) < 2 {
-		return 999.99, errors.New("Problem decoding w1_slave data, not enough lines")
+		return math.NaN(), errors.New("Problem decoding w1_slave data, not enough lines")
+	}
+
+	// the first line ends with the CRC check
+	if !strings.HasSuffix(strings.TrimSpace(lines[0]), "YES") {
+		return math.NaN(), errors.New("Bad w1_slave data CRC")
 	}
 
 	// get 10th element
 	elements := strings.Split(lines[1], " ")
 	if len(elements) < 10 {
-		return 999.99, errors.New("Problem decoding w1_slave data, not enough fields")
+		return math.NaN(), errors.New("Problem decoding w1_slave data, not enough fields")
 	}
 	// remove "t=" and convert to number
 	temp, err := strconv.Atoi(strings.ReplaceAll(elements[9], "t=", ""))
 	if err != nil {
-		return 999.99, err
+		return math.NaN(), err
 	}
 
 	// ok, return the float
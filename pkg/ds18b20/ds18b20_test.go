@@ -2,6 +2,7 @@ package ds18b20
 
 import (
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -9,7 +10,7 @@ func TestGetTemp(t *testing.T) {
 	// do not init, use fake device file
 	ds := DS18B20{
 		Device: "../../testdata/ds18b20test.txt",
-		Temp:   999.99,
+		Temp:   math.NaN(),
 	}
 
 	temp, err := ds.Read()
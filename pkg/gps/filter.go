@@ -0,0 +1,95 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+// after this number of consecutive rejected fixes, the next one within
+// the altitude bounds is accepted without the speed checks, in case the
+// reference fix was the wrong one
+const maxRejections = 10
+
+var ErrFixRejected = errors.New("GPS fix rejected")
+
+// FixState tells if the position is current or held from an older fix
+type FixState int
+
+const (
+	FixStateNone     FixState = iota // no good fix yet
+	FixStateCurrent                  // last update got a good fix
+	FixStateHeld                     // no new fix, holding the last good one
+	FixStateRejected                 // new fix rejected by the filter, holding the last good one
+)
+
+func (s FixState) String() string {
+	switch s {
+	case FixStateCurrent:
+		return "CURRENT"
+	case FixStateHeld:
+		return "HELD"
+	case FixStateRejected:
+		return "REJECTED"
+	default:
+		return "NONE"
+	}
+}
+
+// Filter rejects physically impossible fixes
+type Filter struct {
+	MaxHSpeed float64 // max horizontal speed (m/s), 0 disables the check
+	MaxVSpeed float64 // max vertical speed (m/s), 0 disables the check
+	MinAlt    float64 // altitude bounds (m), disabled if both are 0
+	MaxAlt    float64
+}
+
+// DefaultFilter has generous limits for a balloon flight
+var DefaultFilter = Filter{
+	MaxHSpeed: 150.0,
+	MaxVSpeed: 100.0,
+	MinAlt:    -500.0,
+	MaxAlt:    60000.0,
+}
+
+// Check returns why next is not plausible after the last good fix,
+// or nil if it is
+func (f Filter) Check(last position.Position, next position.Position) error {
+	if err := f.checkAlt(next); err != nil {
+		return err
+	}
+	return f.checkSpeed(last, next)
+}
+
+// checkAlt checks the altitude bounds, for any fix
+func (f Filter) checkAlt(next position.Position) error {
+	if (f.MinAlt != 0 || f.MaxAlt != 0) && (next.Alt < f.MinAlt || next.Alt > f.MaxAlt) {
+		return fmt.Errorf("altitude %.1fm out of bounds", next.Alt)
+	}
+	return nil
+}
+
+// checkSpeed checks the speeds from the last good fix
+func (f Filter) checkSpeed(last position.Position, next position.Position) error {
+	// without fix times we can't check speeds
+	if last.Time.IsZero() || next.Time.IsZero() {
+		return nil
+	}
+	dt := next.Time.Sub(last.Time).Seconds()
+	if dt < 0 {
+		return fmt.Errorf("fix time %v before last fix %v", next.Time, last.Time)
+	}
+	if dt == 0 {
+		// same fix
+		return nil
+	}
+	if hSpeed := last.Distance(next) / dt; f.MaxHSpeed > 0 && hSpeed > f.MaxHSpeed {
+		return fmt.Errorf("horizontal speed %.1fm/s too high", hSpeed)
+	}
+	if vSpeed := math.Abs(next.Alt-last.Alt) / dt; f.MaxVSpeed > 0 && vSpeed > f.MaxVSpeed {
+		return fmt.Errorf("vertical speed %.1fm/s too high", vSpeed)
+	}
+	return nil
+}
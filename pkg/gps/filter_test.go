@@ -0,0 +1,95 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	tr := newTracker()
+	if tr.State() != FixStateNone {
+		t.Errorf("Expected no fix state, got %v", tr.State())
+	}
+
+	tr.handleLine("$GNRMC,123519.00,A,4332.944,N,00539.783,W,022.4,084.4,230394,003.1,W,A*2B", time.Now())
+	tr.handleLine("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75", time.Now())
+	if err := tr.Update(); err != nil || tr.State() != FixStateCurrent {
+		t.Fatalf("Expected current fix, got %v, %v", tr.State(), err)
+	}
+	good := tr.Position()
+
+	// jump of one degree (111km) in one second
+	tr.handleLine("$GPGGA,123520.00,4432.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*78", time.Now())
+	if err := tr.Update(); !errors.Is(err, ErrFixRejected) || tr.State() != FixStateRejected {
+		t.Errorf("Expected rejected fix, got %v, %v", tr.State(), err)
+	}
+	if pos, _ := tr.LastGood(); pos != good {
+		t.Errorf("Expected last good position %v, got %v", good, pos)
+	}
+
+	// plausible
+	tr.handleLine("$GPGGA,123521.00,4332.950,N,00539.783,W,1,08,0.9,550.4,M,46.9,M,,*7F", time.Now())
+	if err := tr.Update(); err != nil || tr.State() != FixStateCurrent {
+		t.Errorf("Expected current fix, got %v, %v", tr.State(), err)
+	}
+
+	// going back in time
+	tr.handleLine("$GPGGA,123518.00,4332.950,N,00539.783,W,1,08,0.9,550.4,M,46.9,M,,*75", time.Now())
+	if err := tr.Update(); !errors.Is(err, ErrFixRejected) {
+		t.Errorf("Expected rejected fix, got %v", err)
+	}
+
+	// lost fix, position held
+	tr.handleLine("$GLGGA,000001.00,,,,,0,00,99.99,,,,,,*7B", time.Now())
+	if err := tr.Update(); err == nil || tr.State() != FixStateHeld {
+		t.Errorf("Expected held fix, got %v, %v", tr.State(), err)
+	}
+	if _, age := tr.LastGood(); age <= 0 {
+		t.Errorf("Expected last good fix age, got %v", age)
+	}
+
+	if err := DefaultFilter.Check(good, good); err != nil {
+		t.Errorf("Expected same fix to be valid: %v", err)
+	}
+}
+
+// testGGA returns a GGA sentence with a fix at 12:35:sec and alt
+func testGGA(sec int, alt float64) string {
+	s := fmt.Sprintf("GPGGA,1235%02d.00,4332.944,N,00539.783,W,1,08,0.9,%.1f,M,46.9,M,,", sec, alt)
+	return fmt.Sprintf("$%s*%02X", s, Checksum(s))
+}
+
+func TestFilterAltBounds(t *testing.T) {
+	tr := newTracker()
+	tr.SetFilter(Filter{MaxHSpeed: 150, MaxVSpeed: 100, MinAlt: -500, MaxAlt: 18000})
+	tr.handleLine("$GNRMC,123519.00,A,4332.944,N,00539.783,W,022.4,084.4,230394,003.1,W,A*2B", time.Now())
+
+	// out of bounds first fix
+	tr.handleLine(testGGA(0, 25000), time.Now())
+	if err := tr.Update(); !errors.Is(err, ErrFixRejected) || tr.State() != FixStateNone {
+		t.Errorf("Expected rejected first fix, got %v, %v", tr.State(), err)
+	}
+	tr.handleLine(testGGA(1, 545.4), time.Now())
+	if err := tr.Update(); err != nil || tr.State() != FixStateCurrent {
+		t.Fatalf("Expected current fix, got %v, %v", tr.State(), err)
+	}
+
+	// more than maxRejections out of bounds fixes are never accepted
+	for i := range maxRejections + 5 {
+		tr.handleLine(testGGA(2+i, 25000), time.Now().Add(time.Duration(i+1)*time.Millisecond))
+		if err := tr.Update(); !errors.Is(err, ErrFixRejected) {
+			t.Fatalf("Out of bounds fix %d accepted", i)
+		}
+	}
+	if tr.Position().Alt != 545.4 {
+		t.Errorf("Reference fix changed: %v", tr.Position())
+	}
+
+	// after them, a fix in bounds is accepted even if too fast from the reference
+	tr.handleLine(testGGA(30, 5000), time.Now().Add(time.Second))
+	if err := tr.Update(); err != nil || tr.Position().Alt != 5000 {
+		t.Errorf("Expected accepted fix after %d rejections, got %v, %v", maxRejections, tr.Position(), err)
+	}
+}
@@ -2,36 +2,29 @@ package gps
 
 import (
 	"errors"
-	"math"
-	"strconv"
-	"strings"
+	"fmt"
+	"sync"
 	"time"
 
 	"go.bug.st/serial"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
 )
 
 const (
-	fieldTime  = 1
-	fieldLat   = 2
-	fieldNS    = 3
-	fieldLon   = 4
-	fieldEW    = 5
-	fieldSats  = 7
-	fieldAlt   = 9
-	fieldSpeed = 7
-	fieldHDG   = 8
-	fieldDate  = 9
-
-	minSats = 4
+	// wait between retries if the serial port fails
+	retryDelay = time.Second
+	// GPS speeds are in knots
+	KnotsPerMs = 1.943844
 )
 
 type GPS interface {
 	Close() error
 	Update() error
-	Lat() float64
-	NS() string
-	Lon() float64
-	EW() string
+	Fix() Fix
+	Position() position.Position
+	LastGood() (position.Position, time.Duration)
+	State() FixState
 	Alt() float64
 	Sats() int
 	Hdg() float64
@@ -40,263 +33,291 @@ type GPS interface {
 	Time() string
 	Hms() (int, int, int, error)
 	Dmy() (int, int, int, error)
+	Quality() int
+	FixType() FixType
+	Status() string
+	HDOP() float64
+	VDOP() float64
+	PDOP() float64
+	Accuracy() (float64, float64)
+	Satellites() []Satellite
+	Health() Health
+	SetPolicy(Policy)
+	SetFilter(Filter)
+	SetPPS(PPS)
+	Errors() []error
 }
 
+// gps reads the serial port in a goroutine, and keeps the latest data
+// in the tracker
 type gps struct {
-	time    string
-	lat     float64
-	ns      string
-	lon     float64
-	ew      string
-	alt     float64
-	sats    int
-	hdg     float64
-	spd     float64
-	lineGGA string
-	lineRMC string
-	date    string
-	port    serial.Port
+	tracker
+	portFile string
+	mode     *serial.Mode
+	bauds    []int // autobaud sequence, nil if disabled
+	baud     int   // index in bauds
+	portMu   sync.Mutex
+	port     serial.Port
+	framer   framer
+	acks     chan UBXMessage
+	done     chan struct{}
+	wg       sync.WaitGroup
 }
 
-func New(portFile string, speed int) (GPS, error) {
-	// default values
+// New opens the GPS serial port and starts reading it. If model is not
+// DynModelNone, the (u-blox) receiver is configured with that dynamic
+// platform model; failing to do it is reported by Errors().
+// If the port fails or the GPS goes silent, the port is reopened. With
+// autobaud, other baud rates are tried if the data received is not valid.
+func New(portFile string, speed int, model DynModel, autobaud bool) (GPS, error) {
 	g := gps{
-		time:    "",
-		lat:     4332.944,
-		ns:      "N",
-		lon:     539.783,
-		ew:      "W",
-		alt:     0.0,
-		hdg:     0.0,
-		sats:    0,
-		spd:     0.0,
-		lineGGA: "",
-		lineRMC: "",
-		date:    "",
-		//port:    port,
+		tracker:  newTracker(),
+		portFile: portFile,
+		acks:     make(chan UBXMessage, 4),
+		done:     make(chan struct{}),
+	}
+	if autobaud {
+		g.bauds = bauds(speed)
 	}
 
 	// prepare port
-	mode := &serial.Mode{
+	g.mode = &serial.Mode{
 		BaudRate: speed,
 		Parity:   serial.NoParity,
 		DataBits: 8,
 		StopBits: serial.OneStopBit,
 	}
 
-	// options := serial.OpenOptions{
-	// 	PortName:        port,
-	// 	BaudRate:        speed,
-	// 	DataBits:        8,
-	// 	StopBits:        1,
-	// 	MinimumReadSize: 1,
-	// }
-
 	// open port
 	var err error
-	g.port, err = serial.Open(portFile, mode)
+	g.port, err = g.open()
 	if err != nil {
 		return nil, err
 	}
 
-	// var err error
-	// g.port, err = serial.Open(options)
-	// if err != nil {
-	// 	return &g, err
-	// }
+	// start reading
+	g.wg.Add(1)
+	go g.run()
+
+	// configure receiver
+	if model != DynModelNone {
+		err = g.setDynModel(model)
+		if err != nil {
+			g.addError(fmt.Errorf("Can't set GPS dynamic model: %w", err))
+		}
+	}
 
 	return &g, nil
 }
 
 func (g *gps) Close() error {
-	err := g.port.Close()
-	if err != nil {
-		return err
+	close(g.done)
+	g.portMu.Lock()
+	var err error
+	if g.port != nil {
+		err = g.port.Close()
 	}
-	// 	var err error
-	// 	if g.port != nil {
-	// 		err = g.port.Close()
-	// 	}
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	return nil
+	g.portMu.Unlock()
+	g.wg.Wait()
+	return err
 }
 
-func (g *gps) Update() error {
-	// Serial buffer
-	buf := make([]byte, 512)
-	char := make([]byte, 1)
+// open opens the serial port with the current mode
+func (g *gps) open() (serial.Port, error) {
+	port, err := serial.Open(g.portFile, g.mode)
+	if err != nil {
+		return nil, err
+	}
+	// don't block forever if the GPS stops sending data
+	err = port.SetReadTimeout(time.Second)
+	if err != nil {
+		port.Close()
+		return nil, err
+	}
+	return port, nil
+}
 
-	// start parsing buffer
-	g.lineGGA = ""
-	for g.lineGGA == "" {
-		n := 0
-		tStart := time.Now()
-		for range 512 {
-			b, err := g.port.Read(char)
-			if err != nil {
-				return err
-			}
-			// if we read something
-			if b > 0 {
-				buf[n] = char[0]
-				n += 1
-			}
-			// timeout
-			if tNow := time.Now(); tNow.Sub(tStart) > (time.Second * 5) {
-				return errors.New("Timeout reading serial port")
-			}
+// run reads the serial port until Close is called, recovering the
+// link if the port fails, the GPS is silent or sends garbage
+func (g *gps) run() {
+	defer g.wg.Done()
+	buf := make([]byte, 128)
+	dog := newWatchdog(time.Now())
+	for {
+		g.portMu.Lock()
+		port := g.port
+		g.portMu.Unlock()
+		// Close closes the port to stop a blocked read
+		n, err := port.Read(buf)
+		select {
+		case <-g.done:
+			return
+		default:
 		}
-
-		// try to find GGA data
-		data := string(buf[:])
-		if strings.Contains(data, "$GNGGA") {
-			//fmt.Println("Found GGA")
-			// cut to the start
-			data = data[strings.Index(data, "$GNGGA"):]
-			// cut to endline
-			if strings.Contains(data, "\n") {
-				data = data[:strings.Index(data, "\n")]
-				g.lineGGA = data
+		if err != nil {
+			g.setError(err)
+			if !g.reopen() {
+				return
 			}
+			dog = newWatchdog(time.Now())
+			continue
 		}
-	}
-
-	// now RMC line
-	g.lineRMC = ""
-	for g.lineRMC == "" {
-		n := 0
-		tStart := time.Now()
-		for range 512 {
-			b, err := g.port.Read(char)
-			if err != nil {
-				return err
+		now := time.Now()
+		if n > 0 {
+			dog.rx = now
+			lines, frames := g.framer.Write(buf[:n])
+			for _, line := range lines {
+				g.handleLine(line, now)
 			}
-			// if we read something
-			if b > 0 {
-				buf[n] = char[0]
-				n += 1
+			for _, frame := range frames {
+				g.handleFrame(frame, now)
 			}
-			// timeout
-			if tNow := time.Now(); tNow.Sub(tStart) > (time.Second * 5) {
-				return errors.New("Timeout reading serial port")
+			// only forward, a partial sentence doesn't reset it
+			if v := g.lastValid(); v.After(dog.valid) {
+				dog.valid = v
 			}
 		}
-		// try to find RMC data
-		data := string(buf[:])
-		if strings.Contains(data, "$GNRMC") {
-			//fmt.Println("Found RMC")
-			// cut to the start
-			data = data[strings.Index(data, "$GNRMC"):]
-			// cut to endline
-			if strings.Contains(data, "\n") {
-				data = data[:strings.Index(data, "\n")]
-				g.lineRMC = data
+
+		health, action := dog.check(now, g.bauds != nil)
+		g.setHealth(health)
+		switch action {
+		case recoverBaud:
+			g.nextBaud()
+			dog.valid = now
+		case recoverReopen:
+			g.setError(errors.New("GPS silent"))
+			if !g.reopen() {
+				return
 			}
+			dog = newWatchdog(time.Now())
 		}
 	}
+}
 
-	// ok we have both lines, parse them
-	ggaData := strings.Split(g.lineGGA, ",")
-	rmcData := strings.Split(g.lineRMC, ",")
-
-	// enough fields?
-	if len(ggaData) >= 9 && len(rmcData) >= 8 {
-		// good fix ?
-		var err error
-		g.sats, err = strconv.Atoi(ggaData[fieldSats])
-		if err != nil {
-			return err
-		}
-		if g.sats < minSats {
-			// not enough sats, but perhaps we can parse time and date
-			g.time = ggaData[fieldTime]
-			g.date = rmcData[fieldDate]
-			return errors.New("Not enough sats")
-		}
-		// ok parse elements if possible, if not provide default values
-		g.time = ggaData[fieldTime]
-		g.lat, err = strconv.ParseFloat(ggaData[fieldLat], 64)
-		if err != nil {
-			g.lat = 0.0
-		}
-		g.ns = ggaData[fieldNS]
-		if g.ns == "" {
-			g.ns = "N"
-		}
-		g.lon, err = strconv.ParseFloat(ggaData[fieldLon], 64)
-		if err != nil {
-			g.lon = 0.0
-		}
-		g.ew = ggaData[fieldEW]
-		if g.ew == "" {
-			g.ew = "W"
-		}
-		g.alt, err = strconv.ParseFloat(ggaData[fieldAlt], 64)
-		if err != nil {
-			g.alt = 0.0
+// reopen closes the port and opens it again, waiting more after each
+// failed attempt. Returns false if the GPS was closed.
+func (g *gps) reopen() bool {
+	g.setHealth(HealthDisconnected)
+	g.portMu.Lock()
+	select {
+	case <-g.done:
+		// already closed by Close
+		g.portMu.Unlock()
+		return false
+	default:
+	}
+	g.port.Close()
+	g.port = nil
+	g.portMu.Unlock()
+	for n := 0; ; n++ {
+		select {
+		case <-g.done:
+			return false
+		case <-time.After(backoff(n)):
 		}
-		g.spd, err = strconv.ParseFloat(rmcData[fieldSpeed], 64)
+		port, err := g.open()
 		if err != nil {
-			g.spd = 0.0
+			g.setError(err)
+			continue
 		}
-		g.hdg, err = strconv.ParseFloat(rmcData[fieldHDG], 64)
-		if err != nil {
-			g.hdg = 0.0
+		g.portMu.Lock()
+		select {
+		case <-g.done:
+			// closed while opening
+			port.Close()
+			g.portMu.Unlock()
+			return false
+		default:
 		}
-		g.date = rmcData[fieldDate]
-
-	} else {
-		return errors.New("GPS parse error, not enough fields")
+		g.port = port
+		g.portMu.Unlock()
+		g.framer = framer{}
+		g.setError(nil)
+		g.setHealth(HealthOK)
+		g.addError(fmt.Errorf("GPS port %s reopened", g.portFile))
+		return true
 	}
-
-	return nil
 }
 
-// getters
-func (g *gps) Lat() float64 { return g.lat }
-func (g *gps) NS() string   { return g.ns }
-func (g *gps) Lon() float64 { return g.lon }
-func (g *gps) EW() string   { return g.ew }
-func (g *gps) Alt() float64 { return g.alt }
-func (g *gps) Sats() int    { return g.sats }
-func (g *gps) Hdg() float64 { return g.hdg }
-func (g *gps) Spd() float64 { return g.spd }
-func (g *gps) Date() string { return g.date }
-func (g *gps) Time() string { return g.time }
+// nextBaud changes the port to the next baud rate of the autobaud sequence
+func (g *gps) nextBaud() {
+	g.baud = (g.baud + 1) % len(g.bauds)
+	g.mode.BaudRate = g.bauds[g.baud]
+	g.portMu.Lock()
+	err := g.port.SetMode(g.mode)
+	g.portMu.Unlock()
+	if err != nil {
+		g.addError(fmt.Errorf("Can't set GPS baud rate %d: %w", g.mode.BaudRate, err))
+		return
+	}
+	g.framer = framer{}
+	g.addError(fmt.Errorf("No valid GPS data, trying %d bauds", g.mode.BaudRate))
+}
 
-func (g *gps) Hms() (int, int, int, error) {
-	if len(g.time) >= 6 {
-		hour, err := strconv.Atoi(g.time[0:2])
-		minute, err := strconv.Atoi(g.time[2:4])
-		second, err := strconv.ParseFloat(g.time[4:], 64)
-		if err != nil {
-			return 0, 0, 0, errors.New("GPS time parse fields error")
+func (g *gps) handleFrame(frame []byte, received time.Time) {
+	msg, err := DecodeUBX(frame)
+	if err != nil {
+		g.addError(err)
+		return
+	}
+	switch msg.Class {
+	case UBX_CLASS_ACK:
+		select {
+		case g.acks <- msg:
+		default:
+		}
+	case UBX_CLASS_NAV:
+		if msg.ID == UBX_NAV_PVT {
+			pvt, err := ParseNavPVT(msg)
+			if err != nil {
+				g.addError(err)
+				return
+			}
+			g.handlePVT(pvt, received)
 		}
-		return hour, minute, int(second), nil
-	} else {
-		return 0, 0, 0, errors.New("GPS time parse error")
 	}
 }
 
-func (g *gps) Dmy() (int, int, int, error) {
-	if len(g.date) >= 6 {
-		day, err := strconv.Atoi(g.date[0:2])
-		month, err := strconv.Atoi(g.date[2:4])
-		year, err := strconv.Atoi(g.date[4:])
+// sendUBX sends an UBX message and waits for its acknowledge
+func (g *gps) sendUBX(msg UBXMessage) error {
+	err := ErrUBXTimeout
+	for range ubxRetries {
+		// discard old acknowledges
+		for len(g.acks) > 0 {
+			<-g.acks
+		}
+		g.portMu.Lock()
+		_, err := g.port.Write(msg.Encode())
+		g.portMu.Unlock()
 		if err != nil {
-			return 0, 0, 0, errors.New("GPS date parse fields error")
+			return err
+		}
+		timeout := time.After(ubxAckTimeout)
+	wait:
+		for {
+			select {
+			case ack := <-g.acks:
+				if acked, ok := ack.ack(msg.Class, msg.ID); ok {
+					if acked {
+						return nil
+					}
+					err = ErrUBXNak
+					break wait
+				}
+			case <-timeout:
+				break wait
+			}
 		}
-		return day, month, year + 2000, nil
-	} else {
-		return 0, 0, 0, errors.New("GPS date parse error")
 	}
+	return err
 }
 
-func NmeaToDec(latlon float64) float64 {
-	degrees := math.Trunc(latlon / 100.0)
-	fraction := (latlon - (degrees * 100.0)) / 60.0
-
-	return degrees + fraction
+// setDynModel configures the receiver dynamic platform model, using
+// CFG-NAV5 or CFG-VALSET for newer receivers
+func (g *gps) setDynModel(model DynModel) error {
+	err := g.sendUBX(CfgNav5(model))
+	if err == nil {
+		return nil
+	}
+	return g.sendUBX(CfgValsetDynModel(model))
 }
@@ -6,7 +6,7 @@ import (
 )
 
 func TestGPS(t *testing.T) {
-	gps, err := New("/dev/serial0", 9600)
+	gps, err := New("/dev/serial0", 9600, DynModelNone, false)
 	if err != nil {
 		t.Errorf("Error starting GPS: %v", err)
 	}
@@ -19,9 +19,7 @@ func TestGPS(t *testing.T) {
 			t.Errorf("Error updating GPS: %v", err)
 		}
 
-		fmt.Printf("%f %s, %f %s, alt: %f, sats: %d\n",
-			NmeaToDec(gps.Lat()), gps.NS(), NmeaToDec(gps.Lon()), gps.EW(), gps.Alt(), gps.Sats(),
-		)
+		fmt.Printf("%v, sats: %d\n", gps.Position(), gps.Sats())
 
 		h, m, s, err := gps.Hms()
 		if err != nil {
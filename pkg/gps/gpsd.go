@@ -0,0 +1,303 @@
+package gps
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultGpsdAddr = "localhost:2947"
+
+	gpsdDialTimeout = time.Second * 5
+	gpsdWatch       = `?WATCH={"enable":true,"json":true};` + "\n"
+)
+
+// gpsd JSON reports, only the fields we use
+type gpsdReport struct {
+	Class string `json:"class"`
+}
+
+// TPV: time, position, velocity
+type gpsdTPV struct {
+	Mode   int       `json:"mode"` // 0 unknown, 1 no fix, 2 2D, 3 3D
+	Status int       `json:"status"`
+	Time   time.Time `json:"time"`
+	Lat    float64   `json:"lat"`
+	Lon    float64   `json:"lon"`
+	Alt    float64   `json:"alt"`    // old gpsd versions, MSL
+	AltMSL *float64  `json:"altMSL"` // gpsd 3.20+
+	Speed  float64   `json:"speed"`  // m/s
+	Track  float64   `json:"track"`
+	Epx    float64   `json:"epx"` // estimated errors (m)
+	Epy    float64   `json:"epy"`
+	Epv    float64   `json:"epv"`
+	Eph    float64   `json:"eph"`
+}
+
+// SKY: satellites and DOPs
+type gpsdSKY struct {
+	HDOP       float64 `json:"hdop"`
+	VDOP       float64 `json:"vdop"`
+	PDOP       float64 `json:"pdop"`
+	USat       *int    `json:"uSat"`
+	Satellites []struct {
+		PRN    int     `json:"PRN"`
+		El     float64 `json:"el"`
+		Az     float64 `json:"az"`
+		SS     float64 `json:"ss"`
+		Used   bool    `json:"used"`
+		GnssID *int    `json:"gnssid"`
+	} `json:"satellites"`
+}
+
+// gpsd gets the GPS data from a gpsd daemon, so the receiver can be
+// shared with other processes (chrony, etc)
+type gpsd struct {
+	tracker
+	addr   string
+	connMu sync.Mutex
+	conn   net.Conn
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewGpsd connects to the gpsd daemon at addr (host:port, DefaultGpsdAddr
+// if empty) and starts watching its reports. If the connection is lost
+// it keeps trying to reconnect, reporting HealthDisconnected meanwhile.
+func NewGpsd(addr string) (GPS, error) {
+	if addr == "" {
+		addr = DefaultGpsdAddr
+	}
+	g := gpsd{
+		tracker: newTracker(),
+		addr:    addr,
+		done:    make(chan struct{}),
+	}
+	conn, err := g.dial()
+	if err != nil {
+		return nil, err
+	}
+	g.conn = conn
+
+	// start reading
+	g.wg.Add(1)
+	go g.run()
+
+	return &g, nil
+}
+
+func (g *gpsd) Close() error {
+	close(g.done)
+	g.connMu.Lock()
+	var err error
+	if g.conn != nil {
+		err = g.conn.Close()
+	}
+	g.connMu.Unlock()
+	g.wg.Wait()
+	return err
+}
+
+// dial connects to gpsd and enables watcher mode
+func (g *gpsd) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", g.addr, gpsdDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte(gpsdWatch)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// run reads reports until Close is called, reconnecting if needed
+func (g *gpsd) run() {
+	defer g.wg.Done()
+	attempt := 0
+	for {
+		g.connMu.Lock()
+		conn := g.conn
+		g.connMu.Unlock()
+		if conn != nil {
+			attempt = 0
+			g.setError(nil)
+			g.setHealth(HealthOK)
+			g.read(conn)
+		}
+		select {
+		case <-g.done:
+			return
+		case <-time.After(backoff(attempt)):
+		}
+		// reconnect
+		conn, err := g.dial()
+		if err != nil {
+			g.setError(err)
+			conn = nil
+			attempt++
+		}
+		g.connMu.Lock()
+		select {
+		case <-g.done:
+			// closed while dialing
+			if conn != nil {
+				conn.Close()
+			}
+			g.connMu.Unlock()
+			return
+		default:
+		}
+		g.conn = conn
+		g.connMu.Unlock()
+	}
+}
+
+// read handles the reports of a connection until it fails
+func (g *gpsd) read(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		g.handleReport(scanner.Bytes(), time.Now())
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = fmt.Errorf("gpsd closed the connection")
+	}
+	select {
+	case <-g.done:
+	default:
+		g.setError(err)
+		g.setHealth(HealthDisconnected)
+		conn.Close()
+	}
+}
+
+// handleReport decodes a gpsd JSON report and updates the latest data
+func (g *gpsd) handleReport(data []byte, received time.Time) {
+	var report gpsdReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		g.addError(fmt.Errorf("Bad gpsd report: %w", err))
+		return
+	}
+	g.setValid(received)
+	switch report.Class {
+	case "TPV":
+		var tpv gpsdTPV
+		if err := json.Unmarshal(data, &tpv); err != nil {
+			g.addError(fmt.Errorf("Bad gpsd TPV report: %w", err))
+			return
+		}
+		g.handleTPV(tpv, received)
+	case "SKY":
+		var sky gpsdSKY
+		if err := json.Unmarshal(data, &sky); err != nil {
+			g.addError(fmt.Errorf("Bad gpsd SKY report: %w", err))
+			return
+		}
+		g.handleSKY(sky, received)
+	}
+}
+
+func (g *gpsd) handleTPV(tpv gpsdTPV, received time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !tpv.Time.IsZero() {
+		tm := tpv.Time.UTC()
+		g.latest.Time = nmeaTime(tm)
+		g.latest.Date = nmeaDate(tm)
+		g.dateTime = g.latest.Time
+	}
+	g.latest.FixType = FixNone
+	g.latest.Quality = 0
+	g.latest.Status = "V"
+	if tpv.Mode == int(Fix2D) || tpv.Mode == int(Fix3D) {
+		g.latest.FixType = FixType(tpv.Mode)
+		g.latest.Quality = 1
+		if tpv.Status == 2 {
+			// DGPS
+			g.latest.Quality = 2
+		}
+		g.latest.Status = "A"
+		g.latest.Lat = tpv.Lat
+		g.latest.Lon = tpv.Lon
+		if tpv.Mode == int(Fix3D) {
+			g.latest.Alt = tpv.Alt
+			if tpv.AltMSL != nil {
+				g.latest.Alt = *tpv.AltMSL
+			}
+		}
+		g.latest.Spd = tpv.Speed * KnotsPerMs
+		g.latest.Hdg = tpv.Track
+	}
+	g.latest.HAcc = tpv.Eph
+	if g.latest.HAcc == 0 && (tpv.Epx != 0 || tpv.Epy != 0) {
+		g.latest.HAcc = max(tpv.Epx, tpv.Epy)
+	}
+	g.latest.VAcc = tpv.Epv
+	g.latest.Received = received
+	g.latest.PPS, _ = pulseFor(g.pps, received)
+}
+
+func (g *gpsd) handleSKY(sky gpsdSKY, received time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if sky.HDOP != 0 {
+		g.latest.HDOP = sky.HDOP
+	}
+	if sky.VDOP != 0 {
+		g.latest.VDOP = sky.VDOP
+	}
+	if sky.PDOP != 0 {
+		g.latest.PDOP = sky.PDOP
+	}
+	// some gpsd versions send DOP only SKY reports
+	if sky.Satellites == nil {
+		return
+	}
+	used := 0
+	sats := make([]Satellite, 0, len(sky.Satellites))
+	for _, s := range sky.Satellites {
+		if s.Used {
+			used++
+		}
+		system := constellation("", s.PRN)
+		if s.GnssID != nil {
+			system = gnssConstellation(*s.GnssID)
+		}
+		sats = append(sats, Satellite{
+			PRN:       s.PRN,
+			System:    system,
+			Elevation: int(s.El),
+			Azimuth:   int(s.Az),
+			SNR:       int(s.SS),
+		})
+	}
+	g.latest.Sats = used
+	if sky.USat != nil {
+		g.latest.Sats = *sky.USat
+	}
+	g.sats.update(sats, received)
+}
+
+// gnssConstellation from the u-blox gnssId used by gpsd
+func gnssConstellation(id int) Constellation {
+	switch id {
+	case 0:
+		return GPSConst
+	case 1:
+		return SBASConst
+	case 2:
+		return GalileoConst
+	case 3:
+		return BeiDouConst
+	case 5:
+		return QZSSConst
+	case 6:
+		return GLONASSConst
+	}
+	return UnknownConst
+}
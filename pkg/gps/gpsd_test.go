@@ -0,0 +1,144 @@
+package gps
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testTPV = `{"class":"TPV","device":"/dev/serial0","mode":3,"time":"2024-03-15T12:35:19.000Z",` +
+		`"lat":43.549067,"lon":-5.663050,"alt":590.4,"altMSL":590.4,"track":45.5,"speed":2.5,"eph":3.2,"epv":5.1}`
+	testSKY = `{"class":"SKY","device":"/dev/serial0","hdop":1.2,"vdop":1.5,"pdop":1.9,"uSat":7,"satellites":[` +
+		`{"PRN":5,"el":45,"az":120,"ss":38,"used":true,"gnssid":0,"svid":5},` +
+		`{"PRN":67,"el":30,"az":200,"ss":31,"used":true,"gnssid":6,"svid":3},` +
+		`{"PRN":12,"el":10,"az":300,"ss":0,"used":false}]}`
+)
+
+// fakeGpsd accepts connections, checks the WATCH command and sends the
+// reports of each session, the last one for the next connections
+func fakeGpsd(t *testing.T, sessions ...[]string) (net.Listener, chan net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't listen: %v", err)
+	}
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for n := 0; ; n++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil || !strings.HasPrefix(line, "?WATCH=") {
+				t.Errorf("Bad watch command: %q, %v", line, err)
+				conn.Close()
+				continue
+			}
+			conn.Write([]byte(`{"class":"VERSION","release":"3.25"}` + "\n"))
+			for _, r := range sessions[min(n, len(sessions)-1)] {
+				conn.Write([]byte(r + "\n"))
+			}
+		}
+	}()
+	return l, conns
+}
+
+func waitUpdate(t *testing.T, g GPS) {
+	start := time.Now()
+	for g.Update() != nil {
+		if time.Since(start) > time.Second*3 {
+			t.Fatalf("Timeout waiting for a fix: %+v", g.Fix())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestGpsd(t *testing.T) {
+	l, _ := fakeGpsd(t, []string{testSKY, testTPV})
+	defer l.Close()
+
+	g, err := NewGpsd(l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting to gpsd: %v", err)
+	}
+	defer g.Close()
+	waitUpdate(t, g)
+	if h := g.Health(); h != HealthOK {
+		t.Errorf("Bad health: %v", h)
+	}
+
+	f := g.Fix()
+	if f.Lat != 43.549067 || f.Lon != -5.663050 || g.Alt() != 590.4 {
+		t.Errorf("Bad position: %+v", f)
+	}
+	if g.Time() != "123519.00" || g.Date() != "150324" {
+		t.Errorf("Bad time: %s %s", g.Time(), g.Date())
+	}
+	if g.FixType() != Fix3D || g.Quality() != 1 || g.Status() != "A" || g.Sats() != 7 {
+		t.Errorf("Bad fix: %+v", f)
+	}
+	if g.HDOP() != 1.2 || g.VDOP() != 1.5 || g.PDOP() != 1.9 {
+		t.Errorf("Bad DOPs: %+v", f)
+	}
+	if hAcc, vAcc := g.Accuracy(); hAcc != 3.2 || vAcc != 5.1 {
+		t.Errorf("Bad accuracy: %f %f", hAcc, vAcc)
+	}
+	if g.Hdg() != 45.5 || g.Spd() != 2.5*KnotsPerMs {
+		t.Errorf("Bad velocity: %f %f", g.Hdg(), g.Spd())
+	}
+	sats := g.Satellites()
+	if len(sats) != 3 || sats[0].System != GLONASSConst || sats[1].System != GPSConst ||
+		sats[1].PRN != 5 || sats[1].SNR != 38 {
+		t.Errorf("Bad satellites: %v", sats)
+	}
+}
+
+func TestGpsdReconnect(t *testing.T) {
+	// a later fix after reconnecting
+	moved := strings.NewReplacer("12:35:19", "12:35:29", `590.4`, `640.4`).Replace(testTPV)
+	l, conns := fakeGpsd(t, []string{testSKY, testTPV}, []string{testSKY, moved})
+	defer l.Close()
+
+	g, err := NewGpsd(l.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting to gpsd: %v", err)
+	}
+	defer g.Close()
+	waitUpdate(t, g)
+
+	// drop the connection, it should reconnect and get a new fix
+	conn := <-conns
+	conn.Close()
+	select {
+	case conn = <-conns:
+		defer conn.Close()
+	case <-time.After(retryDelay * 3):
+		t.Fatal("No reconnection")
+	}
+	start := time.Now()
+	for g.Update() != nil || g.Alt() != 640.4 {
+		if time.Since(start) > time.Second*3 {
+			t.Fatalf("Timeout waiting for the fix after reconnecting: %+v", g.Fix())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if g.Time() != "123529.00" {
+		t.Errorf("Bad time after reconnecting: %s", g.Time())
+	}
+}
+
+func TestGpsdNoDaemon(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can't listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	if _, err := NewGpsd(addr); err == nil {
+		t.Error("Connected to a closed port")
+	}
+}
@@ -0,0 +1,96 @@
+package gps
+
+import (
+	"time"
+)
+
+const (
+	// receiving bytes but no valid data for this time, wrong baud rate?
+	garbageTimeout = time.Second * 3
+	// no bytes at all for this time, reopen the port
+	silenceTimeout = time.Second * 15
+	// max wait between reconnection attempts
+	maxRetryDelay = time.Second * 30
+)
+
+// baud rates tried by the autobaud, after the configured one
+var autoBauds = []int{9600, 38400, 115200, 4800, 57600, 19200, 230400}
+
+// Health of the GPS receiver link
+type Health int
+
+const (
+	HealthOK           Health = iota // receiving valid data
+	HealthNoData                     // nothing received (yet), receiver silent
+	HealthBadData                    // receiving data but no valid sentences, wrong baud rate?
+	HealthDisconnected               // port or connection lost, reconnecting
+)
+
+func (h Health) String() string {
+	switch h {
+	case HealthOK:
+		return "OK"
+	case HealthNoData:
+		return "NODATA"
+	case HealthBadData:
+		return "BADDATA"
+	default:
+		return "DISCONNECTED"
+	}
+}
+
+// what the serial reader should do to recover the link
+type recovery int
+
+const (
+	recoverNone   recovery = iota
+	recoverBaud            // try the next baud rate
+	recoverReopen          // close and reopen the port
+)
+
+// watchdog watches the data received from the serial port
+type watchdog struct {
+	rx    time.Time // last bytes received
+	valid time.Time // last valid sentence or frame
+}
+
+func newWatchdog(now time.Time) watchdog {
+	return watchdog{rx: now, valid: now}
+}
+
+// check returns the link health and the recovery action needed, if any
+func (w *watchdog) check(now time.Time, autobaud bool) (Health, recovery) {
+	if now.Sub(w.rx) > silenceTimeout {
+		return HealthNoData, recoverReopen
+	}
+	if now.Sub(w.valid) > garbageTimeout {
+		if now.Sub(w.rx) > garbageTimeout {
+			// silent, can be a short glitch
+			return HealthNoData, recoverNone
+		}
+		if autobaud {
+			return HealthBadData, recoverBaud
+		}
+		return HealthBadData, recoverNone
+	}
+	return HealthOK, recoverNone
+}
+
+// backoff returns the wait before the reconnection attempt n (from 0)
+func backoff(n int) time.Duration {
+	if n >= 5 {
+		return maxRetryDelay
+	}
+	return min(retryDelay<<n, maxRetryDelay)
+}
+
+// bauds returns the autobaud sequence, starting with the configured speed
+func bauds(speed int) []int {
+	b := []int{speed}
+	for _, s := range autoBauds {
+		if s != speed {
+			b = append(b, s)
+		}
+	}
+	return b
+}
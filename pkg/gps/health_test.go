@@ -0,0 +1,151 @@
+package gps
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// fakePort is a serial port returning its data in small chunks
+type fakePort struct {
+	serial.Port
+	mu     sync.Mutex
+	data   []byte
+	modes  int // SetMode calls
+	closed chan struct{}
+}
+
+func (p *fakePort) Read(b []byte) (int, error) {
+	select {
+	case <-p.closed:
+		return 0, errors.New("port closed")
+	case <-time.After(time.Millisecond * 10):
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := copy(b[:min(len(b), 4)], p.data)
+	p.data = p.data[n:]
+	return n, nil
+}
+
+func (p *fakePort) SetMode(*serial.Mode) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.modes++
+	return nil
+}
+
+func (p *fakePort) Close() error {
+	close(p.closed)
+	return nil
+}
+
+func TestWatchdog(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		rx, valid time.Duration // since start
+		now       time.Duration
+		autobaud  bool
+		health    Health
+		action    recovery
+	}{
+		{0, 0, time.Second, false, HealthOK, recoverNone},
+		{time.Second * 4, time.Second * 3, time.Second * 5, false, HealthOK, recoverNone},
+		// garbage
+		{time.Second * 4, 0, time.Second * 5, false, HealthBadData, recoverNone},
+		{time.Second * 4, 0, time.Second * 5, true, HealthBadData, recoverBaud},
+		// silent
+		{0, 0, time.Second * 5, true, HealthNoData, recoverNone},
+		{0, 0, time.Second * 16, true, HealthNoData, recoverReopen},
+	}
+	for i, test := range tests {
+		w := watchdog{rx: start.Add(test.rx), valid: start.Add(test.valid)}
+		health, action := w.check(start.Add(test.now), test.autobaud)
+		if health != test.health || action != test.action {
+			t.Errorf("%d: got %v, %d, want %v, %d", i, health, action, test.health, test.action)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{1, 2, 4, 8, 16, 30, 30, 30}
+	for n, w := range want {
+		if b := backoff(n); b != w*time.Second {
+			t.Errorf("backoff(%d) = %v, want %v", n, b, w*time.Second)
+		}
+	}
+	if b := backoff(100); b != maxRetryDelay {
+		t.Errorf("backoff(100) = %v", b)
+	}
+}
+
+func TestBauds(t *testing.T) {
+	b := bauds(38400)
+	if len(b) != len(autoBauds) || b[0] != 38400 || b[1] != 9600 {
+		t.Errorf("Bad autobaud sequence: %v", b)
+	}
+	if b := bauds(1200); len(b) != len(autoBauds)+1 || b[0] != 1200 {
+		t.Errorf("Bad autobaud sequence: %v", b)
+	}
+}
+
+func TestTrackerHealth(t *testing.T) {
+	tr := newTracker()
+	if h := tr.Health(); h != HealthNoData {
+		t.Errorf("Health without data: %v", h)
+	}
+	tr.handleLine("$GNGSA,A,3,10,32,27,08,23,,,,,,,,1.65,0.92,1.37,1*01", time.Now())
+	if h := tr.Health(); h != HealthOK {
+		t.Errorf("Health with data: %v", h)
+	}
+	tr.setHealth(HealthDisconnected)
+	if h := tr.Health(); h != HealthDisconnected || h.String() != "DISCONNECTED" {
+		t.Errorf("Health disconnected: %v", h)
+	}
+}
+
+func TestRunPartialReads(t *testing.T) {
+	port := &fakePort{
+		data: []byte("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75\r\n" +
+			"$GPGGA,123520.00,4432.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*78\r\n"),
+		closed: make(chan struct{}),
+	}
+	g := &gps{
+		tracker: newTracker(),
+		mode:    &serial.Mode{BaudRate: 9600},
+		bauds:   bauds(9600),
+		port:    port,
+		acks:    make(chan UBXMessage, 4),
+		done:    make(chan struct{}),
+	}
+	g.wg.Add(1)
+	go g.run()
+
+	// a sentence split in many reads is not garbage
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		port.mu.Lock()
+		left, modes := len(port.data), port.modes
+		port.mu.Unlock()
+		if modes != 0 {
+			t.Fatalf("Baud rate changed with partial reads")
+		}
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Data not read, %d bytes left", left)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	time.Sleep(time.Millisecond * 50)
+	if h := g.Health(); h != HealthOK || g.lastValid().IsZero() {
+		t.Errorf("Health after partial reads: %v", h)
+	}
+	if err := g.Close(); err != nil {
+		t.Errorf("Error closing: %v", err)
+	}
+}
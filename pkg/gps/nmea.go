@@ -0,0 +1,379 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NMEA 0183 sentence parsing. Sentences are parsed without caring about the
+// talker (GP, GL, GA, GB, GN...), so any GNSS receiver works.
+
+const (
+	// GGA fields (after the address field)
+	ggaTime    = 0
+	ggaLat     = 1
+	ggaNS      = 2
+	ggaLon     = 3
+	ggaEW      = 4
+	ggaQuality = 5
+	ggaSats    = 6
+	ggaHDOP    = 7
+	ggaAlt     = 8
+
+	// RMC fields (after the address field)
+	rmcTime   = 0
+	rmcStatus = 1
+	rmcLat    = 2
+	rmcNS     = 3
+	rmcLon    = 4
+	rmcEW     = 5
+	rmcSpeed  = 6
+	rmcHdg    = 7
+	rmcDate   = 8
+
+	// GSA fields (after the address field)
+	gsaFixType = 1
+	gsaPDOP    = 14
+	gsaHDOP    = 15
+	gsaVDOP    = 16
+
+	// max sentence length, the standard says 82 chars but some
+	// receivers send longer proprietary sentences
+	maxSentenceLen = 256
+)
+
+var (
+	ErrNoStart    = errors.New("NMEA sentence doesn't start with $")
+	ErrNoChecksum = errors.New("NMEA sentence without checksum")
+	ErrChecksum   = errors.New("NMEA checksum mismatch")
+	ErrAddress    = errors.New("NMEA sentence with invalid address field")
+	ErrFields     = errors.New("NMEA sentence with not enough fields")
+	ErrValue      = errors.New("NMEA sentence with invalid field value")
+)
+
+// SentenceError is returned when a sentence can't be parsed, and keeps
+// the offending sentence for logging.
+type SentenceError struct {
+	Sentence string
+	Err      error
+}
+
+func (e *SentenceError) Error() string {
+	return fmt.Sprintf("%v: %q", e.Err, e.Sentence)
+}
+
+func (e *SentenceError) Unwrap() error { return e.Err }
+
+// Sentence is a checksum verified NMEA sentence
+type Sentence struct {
+	Talker string   // GP, GN, GL, GA, GB, P (proprietary)...
+	Type   string   // GGA, RMC, GSA...
+	Fields []string // data fields, without the address field
+	Raw    string
+}
+
+// Checksum calculates the NMEA checksum of data (the characters
+// between "$" and "*")
+func Checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum ^= data[i]
+	}
+	return sum
+}
+
+// ParseSentence checks the checksum of a NMEA line and splits it
+// in its fields
+func ParseSentence(line string) (Sentence, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "$") {
+		return Sentence{}, &SentenceError{line, ErrNoStart}
+	}
+
+	// checksum
+	star := strings.LastIndex(line, "*")
+	if star < 0 || len(line)-star != 3 {
+		return Sentence{}, &SentenceError{line, ErrNoChecksum}
+	}
+	sum, err := strconv.ParseUint(line[star+1:], 16, 8)
+	if err != nil {
+		return Sentence{}, &SentenceError{line, ErrNoChecksum}
+	}
+	data := line[1:star]
+	if Checksum(data) != byte(sum) {
+		return Sentence{}, &SentenceError{line, ErrChecksum}
+	}
+
+	// address field
+	fields := strings.Split(data, ",")
+	address := fields[0]
+	s := Sentence{Fields: fields[1:], Raw: line}
+	switch {
+	case strings.HasPrefix(address, "P") && len(address) > 1:
+		s.Talker = "P"
+		s.Type = address[1:]
+	case len(address) == 5:
+		s.Talker = address[:2]
+		s.Type = address[2:]
+	default:
+		return Sentence{}, &SentenceError{line, ErrAddress}
+	}
+
+	return s, nil
+}
+
+// GGA: fix data
+type GGA struct {
+	Time    string
+	Lat     float64
+	NS      string
+	Lon     float64
+	EW      string
+	Quality int
+	Sats    int
+	HDOP    float64
+	Alt     float64
+}
+
+// RMC: recommended minimum data
+type RMC struct {
+	Time   string
+	Status string
+	Lat    float64
+	NS     string
+	Lon    float64
+	EW     string
+	Spd    float64
+	Hdg    float64
+	Date   string
+}
+
+// GSA: DOP and active satellites
+type GSA struct {
+	FixType FixType
+	PDOP    float64
+	HDOP    float64
+	VDOP    float64
+}
+
+func ParseGGA(s Sentence) (GGA, error) {
+	if s.Type != "GGA" || len(s.Fields) <= ggaAlt {
+		return GGA{}, &SentenceError{s.Raw, ErrFields}
+	}
+	f := s.Fields
+	g := GGA{
+		Time: f[ggaTime],
+		NS:   f[ggaNS],
+		EW:   f[ggaEW],
+	}
+	var err error
+	if g.Lat, err = parseFloat(f[ggaLat]); err != nil {
+		return GGA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.Lon, err = parseFloat(f[ggaLon]); err != nil {
+		return GGA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.Quality, err = parseInt(f[ggaQuality]); err != nil {
+		return GGA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.Sats, err = parseInt(f[ggaSats]); err != nil {
+		return GGA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.HDOP, err = parseFloat(f[ggaHDOP]); err != nil {
+		return GGA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.Alt, err = parseFloat(f[ggaAlt]); err != nil {
+		return GGA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	return g, nil
+}
+
+func ParseRMC(s Sentence) (RMC, error) {
+	if s.Type != "RMC" || len(s.Fields) <= rmcDate {
+		return RMC{}, &SentenceError{s.Raw, ErrFields}
+	}
+	f := s.Fields
+	r := RMC{
+		Time:   f[rmcTime],
+		Status: f[rmcStatus],
+		NS:     f[rmcNS],
+		EW:     f[rmcEW],
+		Date:   f[rmcDate],
+	}
+	var err error
+	if r.Lat, err = parseFloat(f[rmcLat]); err != nil {
+		return RMC{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if r.Lon, err = parseFloat(f[rmcLon]); err != nil {
+		return RMC{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if r.Spd, err = parseFloat(f[rmcSpeed]); err != nil {
+		return RMC{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if r.Hdg, err = parseFloat(f[rmcHdg]); err != nil {
+		return RMC{}, &SentenceError{s.Raw, ErrValue}
+	}
+	return r, nil
+}
+
+func ParseGSA(s Sentence) (GSA, error) {
+	if s.Type != "GSA" || len(s.Fields) <= gsaVDOP {
+		return GSA{}, &SentenceError{s.Raw, ErrFields}
+	}
+	f := s.Fields
+	g := GSA{}
+	fixType, err := parseInt(f[gsaFixType])
+	if err != nil {
+		return GSA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	// 1 is no fix
+	if fixType >= int(Fix2D) {
+		g.FixType = FixType(fixType)
+	}
+	if g.PDOP, err = parseFloat(f[gsaPDOP]); err != nil {
+		return GSA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.HDOP, err = parseFloat(f[gsaHDOP]); err != nil {
+		return GSA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	if g.VDOP, err = parseFloat(f[gsaVDOP]); err != nil {
+		return GSA{}, &SentenceError{s.Raw, ErrValue}
+	}
+	return g, nil
+}
+
+// empty fields are valid in NMEA (no data), parse them as zero
+func parseFloat(field string) (float64, error) {
+	if field == "" {
+		return 0.0, nil
+	}
+	return strconv.ParseFloat(field, 64)
+}
+
+func parseInt(field string) (int, error) {
+	if field == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(field)
+}
+
+// timeOfDay converts a NMEA hhmmss.ss time to the time since midnight
+func timeOfDay(tm string) (time.Duration, bool) {
+	if len(tm) < 6 {
+		return 0, false
+	}
+	hour, err1 := strconv.Atoi(tm[0:2])
+	minute, err2 := strconv.Atoi(tm[2:4])
+	second, err3 := strconv.ParseFloat(tm[4:], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second*float64(time.Second)), true
+}
+
+// nmeaTime formats the time of day of t as hhmmss.ss
+func nmeaTime(t time.Time) string {
+	return fmt.Sprintf("%02d%02d%02d.%02d", t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/1e7)
+}
+
+// nmeaDate formats the date of t as ddmmyy
+func nmeaDate(t time.Time) string {
+	return fmt.Sprintf("%02d%02d%02d", t.Day(), t.Month(), t.Year()%100)
+}
+
+// fixTime combines a NMEA ddmmyy date and hhmmss.ss time,
+// returns zero time if they are not valid
+func fixTime(date string, tm string) time.Time {
+	d, err := time.Parse("020106", date)
+	t, ok := timeOfDay(tm)
+	if err != nil || !ok {
+		return time.Time{}
+	}
+	return d.Add(t)
+}
+
+// rolloverDate fixes the date (ddmmyy) received with dateTime to be valid
+// for tm, if midnight was crossed between both sentences
+func rolloverDate(date string, tm string, dateTime string) string {
+	t1, ok1 := timeOfDay(tm)
+	t2, ok2 := timeOfDay(dateTime)
+	d, err := time.Parse("020106", date)
+	if !ok1 || !ok2 || err != nil {
+		return date
+	}
+	switch diff := t1 - t2; {
+	case diff < -12*time.Hour:
+		// time is from after midnight
+		return d.AddDate(0, 0, 1).Format("020106")
+	case diff > 12*time.Hour:
+		// date is from after midnight
+		return d.AddDate(0, 0, -1).Format("020106")
+	}
+	return date
+}
+
+// framer splits the serial byte stream in NMEA lines and UBX frames,
+// keeping incomplete ones between reads
+type framer struct {
+	buf   []byte // NMEA line
+	frame []byte // UBX frame
+	ubx   bool   // receiving an UBX frame
+	sync  bool   // got the first UBX sync char
+}
+
+func (f *framer) Write(data []byte) ([]string, [][]byte) {
+	var lines []string
+	var frames [][]byte
+	for _, b := range data {
+		if f.ubx {
+			f.frame = append(f.frame, b)
+			if len(f.frame) < ubxHeaderLen {
+				continue
+			}
+			length := int(f.frame[4]) | int(f.frame[5])<<8
+			if length > ubxMaxPayload {
+				// garbage
+				f.ubx = false
+			} else if len(f.frame) == ubxHeaderLen+length+ubxChecksumLen {
+				frames = append(frames, append([]byte(nil), f.frame...))
+				f.ubx = false
+			}
+			continue
+		}
+
+		// UBX sync chars
+		if b == ubxSync1 {
+			f.sync = true
+			continue
+		}
+		if f.sync && b == ubxSync2 {
+			f.sync = false
+			f.ubx = true
+			f.frame = append(f.frame[:0], ubxSync1, ubxSync2)
+			continue
+		}
+		f.sync = false
+
+		switch b {
+		case '$':
+			// start of sentence, discard any garbage before it
+			f.buf = f.buf[:0]
+			f.buf = append(f.buf, b)
+		case '\n':
+			if len(f.buf) > 0 {
+				lines = append(lines, string(f.buf))
+			}
+			f.buf = f.buf[:0]
+		case '\r':
+		default:
+			if len(f.buf) > 0 && len(f.buf) < maxSentenceLen {
+				f.buf = append(f.buf, b)
+			}
+		}
+	}
+	return lines, frames
+}
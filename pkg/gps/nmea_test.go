@@ -0,0 +1,89 @@
+package gps
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSentence(t *testing.T) {
+	s, err := ParseSentence("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75\r\n")
+	if err != nil {
+		t.Fatalf("Error parsing sentence: %v", err)
+	}
+	if s.Talker != "GP" || s.Type != "GGA" {
+		t.Errorf("Expected GP GGA, got %s %s", s.Talker, s.Type)
+	}
+
+	gga, err := ParseGGA(s)
+	if err != nil {
+		t.Fatalf("Error parsing GGA: %v", err)
+	}
+	if gga.Lat != 4332.944 || gga.EW != "W" || gga.Sats != 8 || gga.Alt != 545.4 {
+		t.Errorf("Bad GGA data: %+v", gga)
+	}
+
+	// corrupted sentence
+	_, err = ParseSentence("$GPGGA,123519.00,4332.944,N,00539.783,W,1,09,0.9,545.4,M,46.9,M,,*75")
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+
+	// no checksum
+	_, err = ParseSentence("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,")
+	if !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("Expected no checksum error, got %v", err)
+	}
+
+	// no fix, empty fields
+	s, err = ParseSentence("$GLGGA,000001.00,,,,,0,00,99.99,,,,,,*7B")
+	if err != nil {
+		t.Fatalf("Error parsing sentence: %v", err)
+	}
+	gga, err = ParseGGA(s)
+	if err != nil || gga.Sats != 0 || gga.Lat != 0.0 {
+		t.Errorf("Bad empty GGA: %+v, %v", gga, err)
+	}
+
+	s, err = ParseSentence("$GNRMC,123519.00,A,4332.944,N,00539.783,W,022.4,084.4,230394,003.1,W,A*2B")
+	if err != nil {
+		t.Fatalf("Error parsing sentence: %v", err)
+	}
+	rmc, err := ParseRMC(s)
+	if err != nil {
+		t.Fatalf("Error parsing RMC: %v", err)
+	}
+	if rmc.Status != "A" || rmc.Spd != 22.4 || rmc.Hdg != 84.4 || rmc.Date != "230394" {
+		t.Errorf("Bad RMC data: %+v", rmc)
+	}
+}
+
+func TestFramer(t *testing.T) {
+	f := framer{}
+
+	// garbage, and a sentence split between reads
+	lines, _ := f.Write([]byte("\x00\xff9,W,A*2B\r\n$GNRMC,123519.00,A,4332.944,N,"))
+	if len(lines) != 0 {
+		t.Errorf("Expected no lines, got %q", lines)
+	}
+	lines, _ = f.Write([]byte("00539.783,W,022.4,084.4,230394,003.1,W,A*2B\r\n$GPGGA,1"))
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 line, got %q", lines)
+	}
+	if _, err := ParseSentence(lines[0]); err != nil {
+		t.Errorf("Error parsing framed sentence: %v", err)
+	}
+}
+
+func TestRolloverDate(t *testing.T) {
+	// RMC before midnight, GGA after
+	if d := rolloverDate("311224", "000000.00", "235959.00"); d != "010125" {
+		t.Errorf("Expected 010125, got %s", d)
+	}
+	// RMC after midnight, GGA before
+	if d := rolloverDate("010325", "235959.50", "000000.00"); d != "280225" {
+		t.Errorf("Expected 280225, got %s", d)
+	}
+	if d := rolloverDate("150624", "123519.00", "123518.00"); d != "150624" {
+		t.Errorf("Expected 150624, got %s", d)
+	}
+}
@@ -0,0 +1,69 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FixType is the kind of position solution
+type FixType int
+
+const (
+	FixNone FixType = 0
+	Fix2D   FixType = 2
+	Fix3D   FixType = 3
+)
+
+func (f FixType) String() string {
+	switch f {
+	case Fix2D:
+		return "2D"
+	case Fix3D:
+		return "3D"
+	default:
+		return "NONE"
+	}
+}
+
+// ParseFixType returns the fix type from its config name (none, 2d, 3d)
+func ParseFixType(name string) (FixType, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return FixNone, nil
+	case "2d":
+		return Fix2D, nil
+	case "3d":
+		return Fix3D, nil
+	default:
+		return FixNone, fmt.Errorf("Unknown GPS fix type: %s", name)
+	}
+}
+
+// Policy decides if a fix is good enough to update the position
+type Policy struct {
+	MinSats       int
+	MinFixType    FixType // needs GSA or NAV-PVT data
+	MaxHDOP       float64 // 0 disables the check
+	RequireActive bool    // GGA quality > 0 and RMC status A (active)
+}
+
+// DefaultPolicy just checks the number of satellites
+var DefaultPolicy = Policy{MinSats: 4}
+
+// Check returns why the fix is rejected, or nil if it's accepted
+func (p Policy) Check(f Fix) error {
+	if f.Sats < p.MinSats {
+		return errors.New("Not enough sats")
+	}
+	if f.FixType < p.MinFixType {
+		return fmt.Errorf("Fix type %v, %v needed", f.FixType, p.MinFixType)
+	}
+	if p.MaxHDOP > 0 && (f.HDOP == 0 || f.HDOP > p.MaxHDOP) {
+		return fmt.Errorf("HDOP %.2f over %.2f", f.HDOP, p.MaxHDOP)
+	}
+	if p.RequireActive && (f.Quality == 0 || f.Status != "A") {
+		return errors.New("Fix not active")
+	}
+	return nil
+}
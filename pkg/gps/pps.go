@@ -0,0 +1,146 @@
+package gps
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/host/v3"
+)
+
+const (
+	// pulses deviating more than this from one second break the lock
+	ppsMaxJitter = time.Millisecond * 10
+	// consecutive good pulses needed to lock
+	ppsLockPulses = 4
+	// no pulse for this time breaks the lock
+	ppsTimeout = time.Millisecond * 1500
+	// jitter averaging factor (1/n)
+	ppsJitterAvg = 8
+)
+
+// PPS is a one pulse per second input from the GPS receiver, the rising
+// edge marks the start of each GPS second
+type PPS interface {
+	Close() error
+	Last() time.Time // system time of the last pulse
+	Locked() bool
+	Jitter() time.Duration // average deviation of the pulse period
+}
+
+// ppsState keeps the pulse times and computes the jitter and lock
+type ppsState struct {
+	mu     sync.RWMutex
+	last   time.Time
+	jitter time.Duration
+	good   int // consecutive good pulses
+}
+
+// pulse records a pulse received at t
+func (p *ppsState) pulse(t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.last.IsZero() {
+		dev := (t.Sub(p.last) - time.Second).Abs()
+		if dev < ppsMaxJitter {
+			p.good++
+			p.jitter += (dev - p.jitter) / ppsJitterAvg
+		} else {
+			// missed or spurious pulse
+			p.good = 0
+		}
+	}
+	p.last = t
+}
+
+func (p *ppsState) Last() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.last
+}
+
+func (p *ppsState) Jitter() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.jitter
+}
+
+func (p *ppsState) Locked() bool {
+	return p.locked(time.Now())
+}
+
+func (p *ppsState) locked(now time.Time) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.good >= ppsLockPulses && now.Sub(p.last) < ppsTimeout
+}
+
+// pps reads the pulses from a GPIO pin
+type pps struct {
+	ppsState
+	pin  gpio.PinIO
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPPS starts watching the rising edges of the PPS signal in pin
+func NewPPS(pin uint8) (PPS, error) {
+	_, err := host.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	// configure
+	p := pps{done: make(chan struct{})}
+	p.pin = gpioreg.ByName(fmt.Sprintf("%d", pin))
+	if p.pin == nil {
+		return nil, fmt.Errorf("PPS pin %d not found", pin)
+	}
+	err = p.pin.In(gpio.PullDown, gpio.RisingEdge)
+	if err != nil {
+		return nil, err
+	}
+
+	p.wg.Add(1)
+	go p.run()
+
+	return &p, nil
+}
+
+func (p *pps) Close() error {
+	close(p.done)
+	err := p.pin.Halt()
+	p.wg.Wait()
+	return err
+}
+
+func (p *pps) run() {
+	defer p.wg.Done()
+	for {
+		edge := p.pin.WaitForEdge(time.Second)
+		now := time.Now()
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+		if edge {
+			p.pulse(now)
+		}
+	}
+}
+
+// pulseFor returns the pulse that started the second in which a message
+// was received, if the PPS is locked
+func pulseFor(p PPS, received time.Time) (time.Time, bool) {
+	if p == nil || !p.Locked() {
+		return time.Time{}, false
+	}
+	last := p.Last()
+	if since := received.Sub(last); since < 0 || since >= time.Second {
+		return time.Time{}, false
+	}
+	return last, true
+}
@@ -0,0 +1,79 @@
+package gps
+
+import (
+	"testing"
+	"time"
+)
+
+// fakePPS is a PPS without GPIO
+type fakePPS struct {
+	ppsState
+}
+
+func (p *fakePPS) Close() error { return nil }
+
+func TestPPSLock(t *testing.T) {
+	p := ppsState{}
+	start := time.Now().Add(-time.Second * 10)
+
+	// regular pulses with 1ms jitter
+	for i := range ppsLockPulses + 1 {
+		p.pulse(start.Add(time.Second*time.Duration(i) + time.Millisecond*time.Duration(i%2)))
+	}
+	now := p.Last().Add(time.Millisecond * 500)
+	if !p.locked(now) {
+		t.Error("PPS not locked")
+	}
+	if j := p.Jitter(); j <= 0 || j > time.Millisecond {
+		t.Errorf("Bad jitter: %v", j)
+	}
+
+	// pulses stop
+	if p.locked(p.Last().Add(ppsTimeout)) {
+		t.Error("PPS locked without pulses")
+	}
+
+	// missed pulse
+	p.pulse(p.Last().Add(time.Second * 2))
+	if p.locked(p.Last()) {
+		t.Error("PPS locked after a missed pulse")
+	}
+}
+
+func TestPulseFor(t *testing.T) {
+	p := &fakePPS{}
+	start := time.Now()
+	if _, ok := pulseFor(p, start); ok {
+		t.Error("Pulse without lock")
+	}
+	for i := range ppsLockPulses + 1 {
+		p.pulse(start.Add(time.Second * time.Duration(i-ppsLockPulses)))
+	}
+	last := p.Last()
+	if pulse, ok := pulseFor(p, last.Add(time.Millisecond*300)); !ok || pulse != last {
+		t.Errorf("Bad pulse: %v %v", pulse, ok)
+	}
+	if _, ok := pulseFor(p, last.Add(-time.Millisecond)); ok {
+		t.Error("Pulse after the message")
+	}
+	if _, ok := pulseFor(nil, last); ok {
+		t.Error("Pulse without PPS")
+	}
+}
+
+func TestTrackerPPS(t *testing.T) {
+	p := &fakePPS{}
+	now := time.Now()
+	for i := range ppsLockPulses + 1 {
+		p.pulse(now.Add(-time.Millisecond*200 - time.Second*time.Duration(ppsLockPulses-i)))
+	}
+	tr := newTracker()
+	tr.SetPPS(p)
+	tr.handleLine("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75", now)
+	if err := tr.Update(); err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	if f := tr.Fix(); f.PPS != p.Last() {
+		t.Errorf("Fix without PPS time: %+v", f)
+	}
+}
@@ -0,0 +1,152 @@
+package gps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// time between the end of the file and the start of the next loop
+const replayLoopGap = time.Second
+
+// replay is a GPS that reads a recorded NMEA log instead of a serial
+// port, for testing the mission on a desktop
+type replay struct {
+	tracker
+	file  *os.File
+	speed float64
+	loop  bool
+	shift time.Duration // added to the fix times, after each loop
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewReplay replays the NMEA sentences of file. speed is the replay pace,
+// 1 for real time, 10 for ten times faster..., 0 or less doesn't wait
+// between sentences. If loop is true, the file is replayed forever.
+func NewReplay(file string, speed float64, loop bool) (GPS, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	r := replay{
+		tracker: newTracker(),
+		file:    f,
+		speed:   speed,
+		loop:    loop,
+		done:    make(chan struct{}),
+	}
+
+	// start replaying
+	r.wg.Add(1)
+	go r.run()
+
+	return &r, nil
+}
+
+func (r *replay) Close() error {
+	close(r.done)
+	r.wg.Wait()
+	return r.file.Close()
+}
+
+// run replays the file until the end (or forever if looping)
+// or until Close is called
+func (r *replay) run() {
+	defer r.wg.Done()
+	for {
+		span, ok := r.replay()
+		if !ok || !r.loop {
+			return
+		}
+		// the next loop continues after this one, going back in time
+		// the fixes would be rejected
+		r.shift += span + replayLoopGap
+		if _, err := r.file.Seek(0, 0); err != nil {
+			r.setError(err)
+			return
+		}
+	}
+}
+
+// replay reads the file once, returns the time span of the fixes
+// replayed and false if closed
+func (r *replay) replay() (time.Duration, bool) {
+	var last, span time.Duration
+	started := false
+	scanner := bufio.NewScanner(r.file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// wait for the next epoch
+		if t, ok := sentenceTime(line); ok {
+			d := t - last
+			if d < -12*time.Hour {
+				// midnight
+				d += 24 * time.Hour
+			}
+			if started && d > 0 {
+				span += d
+				if r.speed > 0 {
+					select {
+					case <-time.After(time.Duration(float64(d) / r.speed)):
+					case <-r.done:
+						return span, false
+					}
+				}
+			}
+			last = t
+			started = true
+		}
+		select {
+		case <-r.done:
+			return span, false
+		default:
+		}
+		r.handleLine(shiftSentence(line, r.shift), time.Now())
+	}
+	if err := scanner.Err(); err != nil {
+		r.setError(err)
+	}
+	return span, true
+}
+
+// shiftSentence moves the time of GGA sentences and the date and time
+// of RMC sentences, other lines are not changed
+func shiftSentence(line string, shift time.Duration) string {
+	s, err := ParseSentence(line)
+	if err != nil || shift == 0 {
+		return line
+	}
+	switch {
+	case s.Type == "GGA" && len(s.Fields) > ggaTime:
+		tod, ok := timeOfDay(s.Fields[ggaTime])
+		if !ok {
+			return line
+		}
+		s.Fields[ggaTime] = nmeaTime(time.Time{}.Add(tod + shift))
+	case s.Type == "RMC" && len(s.Fields) > rmcDate:
+		t := fixTime(s.Fields[rmcDate], s.Fields[rmcTime])
+		if t.IsZero() {
+			return line
+		}
+		t = t.Add(shift)
+		s.Fields[rmcTime] = nmeaTime(t)
+		s.Fields[rmcDate] = t.Format("020106")
+	default:
+		return line
+	}
+	data := s.Talker + s.Type + "," + strings.Join(s.Fields, ",")
+	return fmt.Sprintf("$%s*%02X", data, Checksum(data))
+}
+
+// sentenceTime returns the time of day of GGA and RMC sentences
+func sentenceTime(line string) (time.Duration, bool) {
+	s, err := ParseSentence(line)
+	if err != nil || (s.Type != "GGA" && s.Type != "RMC") || len(s.Fields) == 0 {
+		return 0, false
+	}
+	return timeOfDay(s.Fields[0])
+}
@@ -0,0 +1,72 @@
+package gps
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	// 10 epochs at one per second, replayed 100 times faster
+	start := time.Now()
+	g, err := NewReplay("../../testdata/flight.nmea", 100, false)
+	if err != nil {
+		t.Fatalf("Error starting replay: %v", err)
+	}
+	defer g.Close()
+
+	for g.Update() != nil || g.Alt() != 590.4 {
+		if time.Since(start) > time.Second*2 {
+			t.Fatalf("Timeout waiting for the end of the replay: %+v", g.Fix())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*80 {
+		t.Errorf("Replay too fast: %v", elapsed)
+	}
+	if g.Time() != "123519.00" || g.Sats() != 8 || g.FixType() != Fix3D || len(g.Satellites()) != 4 {
+		t.Errorf("Bad replayed fix: %+v", g.Fix())
+	}
+	if errs := g.Errors(); len(errs) != 0 {
+		t.Errorf("Replay errors: %v", errs)
+	}
+}
+
+func TestReplayLoop(t *testing.T) {
+	g, err := NewReplay("../../testdata/flight.nmea", 100, true)
+	if err != nil {
+		t.Fatalf("Error starting replay: %v", err)
+	}
+	defer g.Close()
+
+	// the second loop continues after the first one, 12:35:19
+	end := time.Date(2024, 6, 15, 12, 35, 19, 0, time.UTC)
+	start := time.Now()
+	for !g.Position().Time.After(end.Add(replayLoopGap)) {
+		if time.Since(start) > time.Second*2 {
+			t.Fatalf("Timeout waiting for the second loop: %v", g.Position())
+		}
+		if err := g.Update(); errors.Is(err, ErrFixRejected) {
+			t.Fatalf("Looped fix rejected: %v", err)
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func TestShiftSentence(t *testing.T) {
+	shift := time.Hour*12 + time.Second*10
+	rmc := shiftSentence("$GNRMC,123519.00,A,4332.962,N,00539.756,W,012.4,084.4,150624,,,A*54", shift)
+	s, err := ParseSentence(rmc)
+	if err != nil || s.Fields[rmcTime] != "003529.00" || s.Fields[rmcDate] != "160624" {
+		t.Errorf("Bad shifted RMC: %s, %v", rmc, err)
+	}
+	gga := shiftSentence("$GNGGA,123519.00,4332.962,N,00539.756,W,1,08,0.9,590.4,M,46.9,M,,*6F", shift)
+	s, err = ParseSentence(gga)
+	if err != nil || s.Fields[ggaTime] != "003529.00" || s.Fields[ggaAlt] != "590.4" {
+		t.Errorf("Bad shifted GGA: %s, %v", gga, err)
+	}
+	gsa := "$GNGSA,A,3,10,07,05,08,,,,,,,,,1.65,0.92,1.37,1*06"
+	if got := shiftSentence(gsa, shift); got != gsa {
+		t.Errorf("GSA changed: %s", got)
+	}
+}
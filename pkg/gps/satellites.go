@@ -0,0 +1,174 @@
+package gps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// GSV fields (after the address field)
+	gsvInView    = 2
+	gsvFirstSat  = 3
+	gsvSatFields = 4
+
+	// SNR samples kept for each satellite
+	satHistory = 10
+	// satellites not seen for this time are removed
+	satTimeout = time.Second * 30
+)
+
+// Constellation of a satellite
+type Constellation string
+
+const (
+	GPSConst     Constellation = "GPS"
+	GLONASSConst Constellation = "GLONASS"
+	GalileoConst Constellation = "GALILEO"
+	BeiDouConst  Constellation = "BEIDOU"
+	QZSSConst    Constellation = "QZSS"
+	SBASConst    Constellation = "SBAS"
+	UnknownConst Constellation = "UNKNOWN"
+)
+
+// constellation from the talker, or from the NMEA PRN numbering
+// for mixed (GN) talkers
+func constellation(talker string, prn int) Constellation {
+	switch talker {
+	case "GP":
+		if prn >= 33 && prn <= 64 {
+			return SBASConst
+		}
+		return GPSConst
+	case "GL":
+		return GLONASSConst
+	case "GA":
+		return GalileoConst
+	case "GB", "BD":
+		return BeiDouConst
+	case "GQ":
+		return QZSSConst
+	}
+	switch {
+	case prn >= 1 && prn <= 32:
+		return GPSConst
+	case prn >= 33 && prn <= 64:
+		return SBASConst
+	case prn >= 65 && prn <= 96:
+		return GLONASSConst
+	case prn >= 193 && prn <= 200:
+		return QZSSConst
+	case prn >= 201 && prn <= 263:
+		return BeiDouConst
+	case prn >= 301 && prn <= 336:
+		return GalileoConst
+	}
+	return UnknownConst
+}
+
+// Satellite in view
+type Satellite struct {
+	PRN       int
+	System    Constellation
+	Elevation int   // degrees
+	Azimuth   int   // degrees
+	SNR       int   // dB-Hz, 0 if not tracked
+	History   []int // last SNR values, oldest first
+	LastSeen  time.Time
+}
+
+func (s Satellite) String() string {
+	return fmt.Sprintf("%s%d %d/%d %ddB", s.System, s.PRN, s.Elevation, s.Azimuth, s.SNR)
+}
+
+// GSV: satellites in view, each sentence has up to 4 satellites
+type GSV struct {
+	InView int
+	Sats   []Satellite
+}
+
+func ParseGSV(s Sentence) (GSV, error) {
+	if s.Type != "GSV" || len(s.Fields) <= gsvInView {
+		return GSV{}, &SentenceError{s.Raw, ErrFields}
+	}
+	f := s.Fields
+	g := GSV{}
+	var err error
+	if g.InView, err = parseInt(f[gsvInView]); err != nil {
+		return GSV{}, &SentenceError{s.Raw, ErrValue}
+	}
+	// satellites, NMEA 4.1 adds a signal id field at the end
+	for i := gsvFirstSat; i+gsvSatFields <= len(f); i += gsvSatFields {
+		if f[i] == "" {
+			continue
+		}
+		sat := Satellite{}
+		if sat.PRN, err = parseInt(f[i]); err != nil {
+			return GSV{}, &SentenceError{s.Raw, ErrValue}
+		}
+		if sat.Elevation, err = parseInt(f[i+1]); err != nil {
+			return GSV{}, &SentenceError{s.Raw, ErrValue}
+		}
+		if sat.Azimuth, err = parseInt(f[i+2]); err != nil {
+			return GSV{}, &SentenceError{s.Raw, ErrValue}
+		}
+		if sat.SNR, err = parseInt(f[i+3]); err != nil {
+			return GSV{}, &SentenceError{s.Raw, ErrValue}
+		}
+		sat.System = constellation(s.Talker, sat.PRN)
+		g.Sats = append(g.Sats, sat)
+	}
+	return g, nil
+}
+
+// satTable keeps the satellites in view and their SNR history
+type satTable map[string]*Satellite
+
+func (st satTable) update(sats []Satellite, seen time.Time) {
+	for _, sat := range sats {
+		key := fmt.Sprintf("%s%d", sat.System, sat.PRN)
+		old, ok := st[key]
+		if ok {
+			sat.History = old.History
+		}
+		sat.History = append(sat.History, sat.SNR)
+		if len(sat.History) > satHistory {
+			sat.History = sat.History[len(sat.History)-satHistory:]
+		}
+		sat.LastSeen = seen
+		st[key] = &sat
+	}
+	// remove lost satellites
+	for key, sat := range st {
+		if seen.Sub(sat.LastSeen) > satTimeout {
+			delete(st, key)
+		}
+	}
+}
+
+// list returns a copy of the satellites, sorted by constellation and PRN
+func (st satTable) list() []Satellite {
+	sats := make([]Satellite, 0, len(st))
+	for _, sat := range st {
+		s := *sat
+		s.History = append([]int(nil), sat.History...)
+		sats = append(sats, s)
+	}
+	sort.Slice(sats, func(i, j int) bool {
+		if sats[i].System != sats[j].System {
+			return sats[i].System < sats[j].System
+		}
+		return sats[i].PRN < sats[j].PRN
+	})
+	return sats
+}
+
+// SatellitesString formats the satellites SNR for logging
+func SatellitesString(sats []Satellite) string {
+	parts := make([]string, 0, len(sats))
+	for _, sat := range sats {
+		parts = append(parts, sat.String())
+	}
+	return strings.Join(parts, ", ")
+}
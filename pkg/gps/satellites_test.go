@@ -0,0 +1,45 @@
+package gps
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSatellites(t *testing.T) {
+	tr := newTracker()
+	now := time.Now()
+	tr.handleLine("$GPGSV,3,1,11,10,63,137,17,07,61,098,15,05,59,290,20,08,54,157,30*70", now)
+	// NMEA 4.1 with signal id, and not tracked satellite
+	tr.handleLine("$GLGSV,1,1,02,65,20,045,,66,45,120,33,1*78", now)
+	if errs := tr.Errors(); len(errs) != 0 {
+		t.Fatalf("Parse errors: %v", errs)
+	}
+
+	sats := tr.Satellites()
+	if len(sats) != 6 {
+		t.Fatalf("Expected 6 satellites, got %v", sats)
+	}
+	if sats[0].System != GLONASSConst || sats[0].PRN != 65 || sats[0].SNR != 0 {
+		t.Errorf("Bad satellite: %v", sats[0])
+	}
+	if sats[2].System != GPSConst || sats[2].PRN != 5 || sats[2].Elevation != 59 ||
+		sats[2].Azimuth != 290 || sats[2].SNR != 20 {
+		t.Errorf("Bad satellite: %v", sats[2])
+	}
+
+	// history and timeout
+	for i := range satHistory + 2 {
+		tr.handleLine("$GLGSV,1,1,02,65,20,045,,66,45,120,33,1*78", now.Add(time.Duration(i)*time.Second))
+	}
+	tr.handleLine("$GLGSV,1,1,02,65,20,045,,66,45,120,33,1*78", now.Add(time.Minute))
+	sats = tr.Satellites()
+	if len(sats) != 2 {
+		t.Fatalf("Expected 2 satellites, got %v", sats)
+	}
+	if len(sats[1].History) != satHistory || sats[1].History[0] != 33 {
+		t.Errorf("Bad SNR history: %v", sats[1].History)
+	}
+	if s := SatellitesString(sats); s != "GLONASS65 20/45 0dB, GLONASS66 45/120 33dB" {
+		t.Errorf("Bad satellites string: %s", s)
+	}
+}
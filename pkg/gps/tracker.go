@@ -0,0 +1,415 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+const (
+	// data older than this is not considered current
+	staleTimeout = time.Second * 5
+
+	// max parse errors kept between updates
+	maxErrors = 32
+)
+
+// Fix is a snapshot of the GPS data
+type Fix struct {
+	Time     string
+	Date     string
+	Lat      float64 // signed decimal degrees
+	Lon      float64 // signed decimal degrees
+	Alt      float64
+	Sats     int
+	Hdg      float64
+	Spd      float64
+	Quality  int     // GGA fix quality, 0 invalid, 1 GPS, 2 DGPS...
+	FixType  FixType // from GSA or NAV-PVT
+	Status   string  // RMC status, A active, V void
+	HDOP     float64
+	VDOP     float64
+	PDOP     float64
+	HAcc     float64   // estimated horizontal accuracy (m), only from NAV-PVT
+	VAcc     float64   // estimated vertical accuracy (m), only from NAV-PVT
+	Received time.Time // when the GGA sentence of this fix was received
+	PPS      time.Time // PPS pulse starting the second of this fix, zero without PPS lock
+}
+
+// tracker keeps the latest data received from the GPS, written by the
+// reader goroutine, and the snapshot taken by Update, read by the getters.
+// It's safe to use from any goroutine.
+type tracker struct {
+	mu      sync.RWMutex
+	latest  Fix
+	current Fix
+	errs    []error
+	err     error // reader error, if any
+	policy  Policy
+	sats    satTable
+	// time of the sentence with the date, to detect midnight rollovers
+	dateTime string
+	// last accepted position
+	position   position.Position
+	good       time.Time // when the last accepted fix was received
+	state      FixState
+	filter     Filter
+	rejections int
+	rejected   time.Time // last rejected fix
+	// link health, set by the reader
+	health Health
+	valid  time.Time // last valid sentence, frame or report received
+	pps    PPS
+}
+
+func newTracker() tracker {
+	// default values
+	return tracker{
+		policy: DefaultPolicy,
+		filter: DefaultFilter,
+		sats:   satTable{},
+		current: Fix{
+			Lat: 43.549067,
+			Lon: -5.663050,
+		},
+		position: position.Position{
+			Lat: 43.549067,
+			Lon: -5.663050,
+		},
+	}
+}
+
+// handleLine parses a NMEA line and updates the latest data
+func (t *tracker) handleLine(line string, received time.Time) {
+	s, err := ParseSentence(line)
+	if err != nil {
+		t.addError(err)
+		return
+	}
+	t.setValid(received)
+	switch s.Type {
+	case "GGA":
+		gga, err := ParseGGA(s)
+		if err != nil {
+			t.addError(err)
+			return
+		}
+		pos := position.FromNMEA(gga.Lat, gga.NS, gga.Lon, gga.EW, gga.Alt, time.Time{})
+		t.mu.Lock()
+		t.latest.Time = gga.Time
+		t.latest.Lat = pos.Lat
+		t.latest.Lon = pos.Lon
+		t.latest.Alt = gga.Alt
+		t.latest.Sats = gga.Sats
+		t.latest.Quality = gga.Quality
+		t.latest.HDOP = gga.HDOP
+		t.latest.Received = received
+		t.latest.PPS, _ = pulseFor(t.pps, received)
+		t.mu.Unlock()
+	case "RMC":
+		rmc, err := ParseRMC(s)
+		if err != nil {
+			t.addError(err)
+			return
+		}
+		t.mu.Lock()
+		t.latest.Date = rmc.Date
+		t.dateTime = rmc.Time
+		t.latest.Spd = rmc.Spd
+		t.latest.Hdg = rmc.Hdg
+		t.latest.Status = rmc.Status
+		t.mu.Unlock()
+	case "GSA":
+		gsa, err := ParseGSA(s)
+		if err != nil {
+			t.addError(err)
+			return
+		}
+		t.mu.Lock()
+		t.latest.FixType = gsa.FixType
+		t.latest.PDOP = gsa.PDOP
+		t.latest.HDOP = gsa.HDOP
+		t.latest.VDOP = gsa.VDOP
+		t.mu.Unlock()
+	case "GSV":
+		gsv, err := ParseGSV(s)
+		if err != nil {
+			t.addError(err)
+			return
+		}
+		t.mu.Lock()
+		t.sats.update(gsv.Sats, received)
+		t.mu.Unlock()
+	}
+}
+
+// handlePVT updates the latest data from an UBX NAV-PVT solution
+func (t *tracker) handlePVT(pvt NavPVT, received time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.latest.Time = nmeaTime(pvt.Time)
+	t.latest.Date = nmeaDate(pvt.Time)
+	t.dateTime = t.latest.Time
+	t.latest.Lat = pvt.Lat
+	t.latest.Lon = pvt.Lon
+	t.latest.Alt = pvt.Alt
+	t.latest.Sats = pvt.Sats
+	t.latest.Spd = pvt.Spd
+	t.latest.Hdg = pvt.Hdg
+	t.latest.PDOP = pvt.PDOP
+	t.latest.HAcc = pvt.HAcc
+	t.latest.VAcc = pvt.VAcc
+	t.latest.FixType = FixNone
+	t.latest.Quality = 0
+	t.latest.Status = "V"
+	if pvt.Flags&0x01 != 0 {
+		if pvt.FixType == byte(Fix2D) || pvt.FixType == byte(Fix3D) {
+			t.latest.FixType = FixType(pvt.FixType)
+		}
+		t.latest.Quality = 1
+		t.latest.Status = "A"
+	}
+	t.latest.Received = received
+	t.latest.PPS, _ = pulseFor(t.pps, received)
+	t.valid = received
+}
+
+func (t *tracker) addError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.errs) < maxErrors {
+		t.errs = append(t.errs, err)
+	}
+}
+
+func (t *tracker) setError(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+}
+
+func (t *tracker) setHealth(h Health) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.health = h
+}
+
+func (t *tracker) setValid(received time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.valid = received
+}
+
+func (t *tracker) lastValid() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.valid
+}
+
+// Update takes a snapshot of the latest data received, it doesn't block
+func (t *tracker) Update() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// until we get a new good fix
+	if t.state != FixStateNone {
+		t.state = FixStateHeld
+	}
+
+	if t.err != nil {
+		return t.err
+	}
+	if t.latest.Received.IsZero() {
+		return errors.New("No GPS data received yet")
+	}
+	if time.Since(t.latest.Received) > staleTimeout {
+		return errors.New("GPS data too old")
+	}
+
+	// good fix ?
+	date := rolloverDate(t.latest.Date, t.latest.Time, t.dateTime)
+	t.current.Sats = t.latest.Sats
+	t.current.Time = t.latest.Time
+	t.current.Date = date
+	t.current.Quality = t.latest.Quality
+	t.current.FixType = t.latest.FixType
+	t.current.Status = t.latest.Status
+	t.current.HDOP = t.latest.HDOP
+	t.current.VDOP = t.latest.VDOP
+	t.current.PDOP = t.latest.PDOP
+	t.current.HAcc = t.latest.HAcc
+	t.current.VAcc = t.latest.VAcc
+	t.current.Received = t.latest.Received
+	t.current.PPS = t.latest.PPS
+	if err := t.policy.Check(t.latest); err != nil {
+		// not good enough, but we have time, date and fix quality
+		return err
+	}
+
+	// plausible ?
+	pos := position.Position{
+		Lat:  t.latest.Lat,
+		Lon:  t.latest.Lon,
+		Alt:  t.latest.Alt,
+		Time: fixTime(date, t.latest.Time),
+	}
+	// altitude bounds always, speeds from the last good fix
+	err := t.filter.checkAlt(pos)
+	if err == nil && t.state != FixStateNone && t.rejections < maxRejections {
+		err = t.filter.checkSpeed(t.position, pos)
+	}
+	if err != nil {
+		// count each fix once
+		if t.rejected != t.latest.Received {
+			t.rejections++
+			t.rejected = t.latest.Received
+		}
+		if t.state != FixStateNone {
+			t.state = FixStateRejected
+		}
+		return fmt.Errorf("%w: %v", ErrFixRejected, err)
+	}
+
+	// ok update elements
+	t.current = t.latest
+	t.current.Date = date
+	t.position = pos
+	t.good = t.latest.Received
+	t.state = FixStateCurrent
+	t.rejections = 0
+
+	return nil
+}
+
+// getters
+func (t *tracker) Fix() Fix {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.current
+}
+
+// Position returns the last accepted position
+func (t *tracker) Position() position.Position {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.position
+}
+
+// LastGood returns the last accepted position and its age
+func (t *tracker) LastGood() (position.Position, time.Duration) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.good.IsZero() {
+		return t.position, 0
+	}
+	return t.position, time.Since(t.good)
+}
+
+// State tells if the position is current, held or the new fix was rejected
+func (t *tracker) State() FixState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state
+}
+
+func (t *tracker) Alt() float64 { return t.Position().Alt }
+func (t *tracker) Sats() int    { return t.Fix().Sats }
+func (t *tracker) Hdg() float64 { return t.Fix().Hdg }
+func (t *tracker) Spd() float64 { return t.Fix().Spd }
+func (t *tracker) Date() string { return t.Fix().Date }
+func (t *tracker) Time() string { return t.Fix().Time }
+
+func (t *tracker) Quality() int     { return t.Fix().Quality }
+func (t *tracker) FixType() FixType { return t.Fix().FixType }
+func (t *tracker) Status() string   { return t.Fix().Status }
+func (t *tracker) HDOP() float64    { return t.Fix().HDOP }
+func (t *tracker) VDOP() float64    { return t.Fix().VDOP }
+func (t *tracker) PDOP() float64    { return t.Fix().PDOP }
+func (t *tracker) Accuracy() (float64, float64) {
+	f := t.Fix()
+	return f.HAcc, f.VAcc
+}
+
+// Satellites returns the satellites currently in view
+func (t *tracker) Satellites() []Satellite {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.sats.list()
+}
+
+// Health returns the receiver link health
+func (t *tracker) Health() Health {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.health != HealthOK {
+		return t.health
+	}
+	if t.valid.IsZero() || time.Since(t.valid) > staleTimeout {
+		return HealthNoData
+	}
+	return HealthOK
+}
+
+// SetPPS sets the PPS input used to timestamp the fixes
+func (t *tracker) SetPPS(p PPS) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pps = p
+}
+
+// SetFilter changes the fix plausibility filter
+func (t *tracker) SetFilter(f Filter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.filter = f
+}
+
+// SetPolicy changes the fix acceptance policy
+func (t *tracker) SetPolicy(p Policy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policy = p
+}
+
+// parse errors found since the last call
+func (t *tracker) Errors() []error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	errs := t.errs
+	t.errs = nil
+	return errs
+}
+
+func (t *tracker) Hms() (int, int, int, error) {
+	tm := t.Time()
+	if len(tm) >= 6 {
+		hour, err1 := strconv.Atoi(tm[0:2])
+		minute, err2 := strconv.Atoi(tm[2:4])
+		second, err3 := strconv.ParseFloat(tm[4:], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return 0, 0, 0, errors.New("GPS time parse fields error")
+		}
+		return hour, minute, int(second), nil
+	} else {
+		return 0, 0, 0, errors.New("GPS time parse error")
+	}
+}
+
+func (t *tracker) Dmy() (int, int, int, error) {
+	date := t.Date()
+	if len(date) >= 6 {
+		day, err1 := strconv.Atoi(date[0:2])
+		month, err2 := strconv.Atoi(date[2:4])
+		year, err3 := strconv.Atoi(date[4:])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return 0, 0, 0, errors.New("GPS date parse fields error")
+		}
+		return day, month, year + 2000, nil
+	} else {
+		return 0, 0, 0, errors.New("GPS date parse error")
+	}
+}
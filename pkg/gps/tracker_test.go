@@ -0,0 +1,93 @@
+package gps
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	tr := newTracker()
+
+	if err := tr.Update(); err == nil {
+		t.Errorf("Expected error without data")
+	}
+
+	// feed and read from different goroutines
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			tr.handleLine("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75", time.Now())
+			tr.handleLine("$GNRMC,123519.00,A,4332.944,N,00539.783,W,022.4,084.4,230394,003.1,W,A*2B", time.Now())
+		}
+	}()
+	for range 100 {
+		tr.Update()
+		tr.Position()
+	}
+	wg.Wait()
+
+	if err := tr.Update(); err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	pos := tr.Position()
+	if math.Abs(pos.Lat-43.549067) > 1e-6 || math.Abs(pos.Lon+5.663050) > 1e-6 ||
+		pos.Time != time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC) {
+		t.Errorf("Bad position: %+v", pos)
+	}
+	fix := tr.Fix()
+	if fix.Alt != 545.4 || fix.Sats != 8 || fix.Spd != 22.4 || fix.Date != "230394" {
+		t.Errorf("Bad fix data: %+v", fix)
+	}
+	h, m, s, err := tr.Hms()
+	if err != nil || h != 12 || m != 35 || s != 19 {
+		t.Errorf("Bad time %d:%d:%d, %v", h, m, s, err)
+	}
+
+	// parse errors are reported once
+	tr.handleLine("$GPGGA,123519.00,4332.944,N,00539.783,W,1,09,0.9,545.4,M,46.9,M,,*75", time.Now())
+	if errs := tr.Errors(); len(errs) != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+	if errs := tr.Errors(); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	// stale data
+	tr.handleLine("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75", time.Now().Add(-time.Minute))
+	if err := tr.Update(); err == nil {
+		t.Errorf("Expected error with stale data")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	tr := newTracker()
+	tr.SetPolicy(Policy{MinSats: 4, MinFixType: Fix3D, MaxHDOP: 2.0, RequireActive: true})
+
+	tr.handleLine("$GPGGA,123519.00,4332.944,N,00539.783,W,1,08,0.9,545.4,M,46.9,M,,*75", time.Now())
+	tr.handleLine("$GNRMC,123519.00,A,4332.944,N,00539.783,W,022.4,084.4,230394,003.1,W,A*2B", time.Now())
+	// no GSA yet, fix type unknown
+	if err := tr.Update(); err == nil {
+		t.Errorf("Expected fix type error")
+	}
+
+	tr.handleLine("$GNGSA,A,3,10,32,27,08,23,,,,,,,,1.65,0.92,1.37,1*01", time.Now())
+	if err := tr.Update(); err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	if tr.FixType() != Fix3D || tr.Quality() != 1 || tr.Status() != "A" ||
+		tr.PDOP() != 1.65 || tr.HDOP() != 0.92 || tr.VDOP() != 1.37 {
+		t.Errorf("Bad fix quality: %+v", tr.Fix())
+	}
+
+	tr.handleLine("$GPGSA,A,1,,,,,,,,,,,,,99.99,99.99,99.99*30", time.Now())
+	if err := tr.Update(); err == nil {
+		t.Errorf("Expected error without fix")
+	}
+	if tr.FixType() != FixNone {
+		t.Errorf("Expected no fix, got %v", tr.FixType())
+	}
+}
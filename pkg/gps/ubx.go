@@ -0,0 +1,206 @@
+package gps
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// u-blox UBX binary protocol
+
+const (
+	ubxSync1 byte = 0xB5
+	ubxSync2 byte = 0x62
+
+	// header (sync, class, id, length) and checksum sizes
+	ubxHeaderLen   = 6
+	ubxChecksumLen = 2
+	ubxMaxPayload  = 1024
+
+	// message classes and ids
+	UBX_CLASS_NAV  byte = 0x01
+	UBX_CLASS_ACK  byte = 0x05
+	UBX_CLASS_CFG  byte = 0x06
+	UBX_NAV_PVT    byte = 0x07
+	UBX_ACK_NAK    byte = 0x00
+	UBX_ACK_ACK    byte = 0x01
+	UBX_CFG_NAV5   byte = 0x24
+	UBX_CFG_VALSET byte = 0x8A
+
+	// CFG-NAV5
+	cfgNav5Len     = 36
+	cfgNav5MaskDyn = 0x0001
+
+	// CFG-VALSET, configuration in RAM and battery backed RAM
+	cfgValsetLayers     = 0x03
+	cfgNavspgDynmodel   = 0x20110021
+	navPVTLen           = 92
+	ubxAckTimeout       = time.Second
+	ubxRetries          = 3
+	knotsPerMmPerSecond = 0.001 * KnotsPerMs
+)
+
+// DynModel is the receiver dynamic platform model
+type DynModel int
+
+const (
+	DynModelNone       DynModel = -1 // don't configure the receiver
+	DynModelPortable   DynModel = 0
+	DynModelStationary DynModel = 2
+	DynModelPedestrian DynModel = 3
+	DynModelAutomotive DynModel = 4
+	DynModelSea        DynModel = 5
+	DynModelAirborne1g DynModel = 6 // high altitude balloons
+	DynModelAirborne2g DynModel = 7
+	DynModelAirborne4g DynModel = 8
+)
+
+var dynModelNames = map[string]DynModel{
+	"":           DynModelNone,
+	"none":       DynModelNone,
+	"portable":   DynModelPortable,
+	"stationary": DynModelStationary,
+	"pedestrian": DynModelPedestrian,
+	"automotive": DynModelAutomotive,
+	"sea":        DynModelSea,
+	"airborne1g": DynModelAirborne1g,
+	"airborne2g": DynModelAirborne2g,
+	"airborne4g": DynModelAirborne4g,
+}
+
+var (
+	ErrUBXChecksum = errors.New("UBX checksum mismatch")
+	ErrUBXFrame    = errors.New("Invalid UBX frame")
+	ErrUBXNak      = errors.New("UBX message not acknowledged (NAK)")
+	ErrUBXTimeout  = errors.New("Timeout waiting for UBX acknowledge")
+)
+
+// ParseDynModel returns the dynamic model from its config name
+// (portable, airborne1g...), empty or "none" leave the receiver as is
+func ParseDynModel(name string) (DynModel, error) {
+	model, ok := dynModelNames[strings.ToLower(name)]
+	if !ok {
+		return DynModelNone, fmt.Errorf("Unknown GPS dynamic model: %s", name)
+	}
+	return model, nil
+}
+
+// UBXMessage is an UBX message without framing
+type UBXMessage struct {
+	Class   byte
+	ID      byte
+	Payload []byte
+}
+
+// ubxChecksum calculates the 8-bit Fletcher checksum of class, id,
+// length and payload
+func ubxChecksum(data []byte) (byte, byte) {
+	var a, b byte
+	for _, d := range data {
+		a += d
+		b += a
+	}
+	return a, b
+}
+
+// Encode creates the UBX frame of the message
+func (m UBXMessage) Encode() []byte {
+	frame := make([]byte, 0, ubxHeaderLen+len(m.Payload)+ubxChecksumLen)
+	frame = append(frame, ubxSync1, ubxSync2, m.Class, m.ID)
+	frame = binary.LittleEndian.AppendUint16(frame, uint16(len(m.Payload)))
+	frame = append(frame, m.Payload...)
+	a, b := ubxChecksum(frame[2:])
+	return append(frame, a, b)
+}
+
+// DecodeUBX checks and decodes an UBX frame
+func DecodeUBX(frame []byte) (UBXMessage, error) {
+	if len(frame) < ubxHeaderLen+ubxChecksumLen ||
+		frame[0] != ubxSync1 || frame[1] != ubxSync2 {
+		return UBXMessage{}, ErrUBXFrame
+	}
+	length := int(binary.LittleEndian.Uint16(frame[4:6]))
+	if len(frame) != ubxHeaderLen+length+ubxChecksumLen {
+		return UBXMessage{}, ErrUBXFrame
+	}
+	a, b := ubxChecksum(frame[2 : ubxHeaderLen+length])
+	if a != frame[len(frame)-2] || b != frame[len(frame)-1] {
+		return UBXMessage{}, ErrUBXChecksum
+	}
+	return UBXMessage{
+		Class:   frame[2],
+		ID:      frame[3],
+		Payload: frame[ubxHeaderLen : ubxHeaderLen+length],
+	}, nil
+}
+
+// CfgNav5 creates a CFG-NAV5 message setting only the dynamic model
+// (u-blox 6 to 8 receivers)
+func CfgNav5(model DynModel) UBXMessage {
+	payload := make([]byte, cfgNav5Len)
+	binary.LittleEndian.PutUint16(payload[0:2], cfgNav5MaskDyn)
+	payload[2] = byte(model)
+	return UBXMessage{Class: UBX_CLASS_CFG, ID: UBX_CFG_NAV5, Payload: payload}
+}
+
+// CfgValsetDynModel creates a CFG-VALSET message setting the dynamic
+// model (u-blox 9 and 10 receivers)
+func CfgValsetDynModel(model DynModel) UBXMessage {
+	payload := []byte{0x00, cfgValsetLayers, 0x00, 0x00}
+	payload = binary.LittleEndian.AppendUint32(payload, cfgNavspgDynmodel)
+	payload = append(payload, byte(model))
+	return UBXMessage{Class: UBX_CLASS_CFG, ID: UBX_CFG_VALSET, Payload: payload}
+}
+
+// ack returns if the message is an ACK-ACK or ACK-NAK for the
+// message with class and id
+func (m UBXMessage) ack(class, id byte) (acked bool, ok bool) {
+	if m.Class != UBX_CLASS_ACK || len(m.Payload) < 2 ||
+		m.Payload[0] != class || m.Payload[1] != id {
+		return false, false
+	}
+	return m.ID == UBX_ACK_ACK, true
+}
+
+// NavPVT: navigation position, velocity and time solution
+type NavPVT struct {
+	Time    time.Time // UTC
+	Valid   byte      // validity flags (date, time, fully resolved)
+	FixType byte      // 0 no fix, 2 2D, 3 3D...
+	Flags   byte      // bit 0: gnssFixOK
+	Sats    int
+	Lat     float64 // decimal degrees
+	Lon     float64 // decimal degrees
+	Alt     float64 // over mean sea level, m
+	HAcc    float64 // m
+	VAcc    float64 // m
+	Spd     float64 // ground speed, knots
+	Hdg     float64 // heading of motion, degrees
+	PDOP    float64
+}
+
+func ParseNavPVT(m UBXMessage) (NavPVT, error) {
+	if m.Class != UBX_CLASS_NAV || m.ID != UBX_NAV_PVT || len(m.Payload) < navPVTLen {
+		return NavPVT{}, ErrUBXFrame
+	}
+	p := m.Payload
+	le := binary.LittleEndian
+	return NavPVT{
+		Time: time.Date(int(le.Uint16(p[4:6])), time.Month(p[6]), int(p[7]),
+			int(p[8]), int(p[9]), int(p[10]), int(int32(le.Uint32(p[16:20]))), time.UTC),
+		Valid:   p[11],
+		FixType: p[20],
+		Flags:   p[21],
+		Sats:    int(p[23]),
+		Lon:     float64(int32(le.Uint32(p[24:28]))) * 1e-7,
+		Lat:     float64(int32(le.Uint32(p[28:32]))) * 1e-7,
+		Alt:     float64(int32(le.Uint32(p[36:40]))) / 1000.0,
+		HAcc:    float64(le.Uint32(p[40:44])) / 1000.0,
+		VAcc:    float64(le.Uint32(p[44:48])) / 1000.0,
+		Spd:     float64(int32(le.Uint32(p[60:64]))) * knotsPerMmPerSecond,
+		Hdg:     float64(int32(le.Uint32(p[64:68]))) * 1e-5,
+		PDOP:    float64(le.Uint16(p[76:78])) * 0.01,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package gps
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUBX(t *testing.T) {
+	// UBX-CFG-NAV5 poll, known frame
+	poll := UBXMessage{Class: UBX_CLASS_CFG, ID: UBX_CFG_NAV5}.Encode()
+	expected := []byte{0xB5, 0x62, 0x06, 0x24, 0x00, 0x00, 0x2A, 0x84}
+	if string(poll) != string(expected) {
+		t.Errorf("Expected % x, got % x", expected, poll)
+	}
+
+	frame := CfgNav5(DynModelAirborne1g).Encode()
+	msg, err := DecodeUBX(frame)
+	if err != nil {
+		t.Fatalf("Error decoding UBX frame: %v", err)
+	}
+	if msg.Class != UBX_CLASS_CFG || msg.ID != UBX_CFG_NAV5 ||
+		len(msg.Payload) != cfgNav5Len || msg.Payload[2] != byte(DynModelAirborne1g) {
+		t.Errorf("Bad CFG-NAV5 message: %+v", msg)
+	}
+
+	// corrupted frame
+	frame[10] ^= 0xff
+	if _, err := DecodeUBX(frame); !errors.Is(err, ErrUBXChecksum) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+
+	// ACK mixed with NMEA in the serial stream
+	ack := UBXMessage{Class: UBX_CLASS_ACK, ID: UBX_ACK_ACK, Payload: []byte{UBX_CLASS_CFG, UBX_CFG_NAV5}}.Encode()
+	f := framer{}
+	stream := append([]byte("$GNRMC,123519.00,A,4332.944,N,"), ack...)
+	stream = append(stream, []byte("00539.783,W,022.4,084.4,230394,003.1,W,A*2B\r\n")...)
+	lines, frames := f.Write(stream)
+	if len(lines) != 1 || len(frames) != 1 {
+		t.Fatalf("Expected 1 line and 1 frame, got %q and % x", lines, frames)
+	}
+	msg, err = DecodeUBX(frames[0])
+	if err != nil {
+		t.Fatalf("Error decoding UBX ack: %v", err)
+	}
+	if acked, ok := msg.ack(UBX_CLASS_CFG, UBX_CFG_NAV5); !acked || !ok {
+		t.Errorf("Expected ACK, got %+v", msg)
+	}
+
+	if model, err := ParseDynModel("Airborne1g"); err != nil || model != DynModelAirborne1g {
+		t.Errorf("Bad dynamic model %v, %v", model, err)
+	}
+}
+
+func TestNavPVT(t *testing.T) {
+	p := make([]byte, navPVTLen)
+	le := binary.LittleEndian
+	pvtLon := int32(-56630500)
+	le.PutUint16(p[4:6], 2024)
+	p[6], p[7], p[8], p[9], p[10] = 6, 15, 10, 20, 30
+	p[20] = 3
+	p[23] = 9
+	le.PutUint32(p[24:28], uint32(pvtLon))
+	le.PutUint32(p[28:32], uint32(int32(435490667)))
+	le.PutUint32(p[36:40], uint32(int32(25000500)))
+	le.PutUint32(p[60:64], uint32(int32(10000)))
+
+	pvt, err := ParseNavPVT(UBXMessage{Class: UBX_CLASS_NAV, ID: UBX_NAV_PVT, Payload: p})
+	if err != nil {
+		t.Fatalf("Error parsing NAV-PVT: %v", err)
+	}
+	if pvt.FixType != 3 || pvt.Sats != 9 || pvt.Alt != 25000.5 ||
+		math.Abs(pvt.Lat-43.5490667) > 1e-9 || math.Abs(pvt.Lon+5.66305) > 1e-9 {
+		t.Errorf("Bad NAV-PVT data: %+v", pvt)
+	}
+	if pvt.Time.Hour() != 10 || pvt.Time.Day() != 15 {
+		t.Errorf("Bad NAV-PVT time: %v", pvt.Time)
+	}
+}
@@ -0,0 +1,193 @@
+package kalman
+
+import (
+	"math"
+	"time"
+)
+
+// Kalman filter for the altitude and vertical speed, fusing the GPS
+// altitude and the barometric altitude. The state is the altitude, the
+// vertical speed and the barometric altitude bias, so the barometer keeps
+// the altitude when the GPS fix is lost and the GPS corrects the drift of
+// the sea level pressure reference.
+
+const (
+	// default acceleration noise (m/s^2), balloons don't accelerate much
+	// but the burst changes the vertical speed in a few seconds
+	DefaultAccelNoise = 2.0
+
+	// barometric bias random walk (m^2/s)
+	biasNoise = 0.05
+	// initial uncertainties of the vertical speed (m/s) and baro bias (m)
+	initVSpeed = 10.0
+	initBias   = 200.0
+	// measurements deviating more than this number of sigmas are rejected
+	gate = 5.0
+	// consecutive rejected measurements to restart the filter
+	maxRejects = 5
+)
+
+// state indexes
+const (
+	iAlt = iota
+	iVSpeed
+	iBias
+)
+
+type Filter interface {
+	UpdateGPS(t time.Time, alt float64, sigma float64) bool
+	UpdateBaro(t time.Time, alt float64, sigma float64) bool
+	Ready() bool
+	Altitude() float64
+	VSpeed() float64
+	Bias() float64
+}
+
+type filter struct {
+	accel   float64
+	x       [3]float64
+	p       [3][3]float64
+	t       time.Time
+	ready   bool
+	rejects int
+}
+
+// New returns a filter with the acceleration noise (m/s^2), or the
+// default if 0
+func New(accel float64) Filter {
+	if accel <= 0 {
+		accel = DefaultAccelNoise
+	}
+	return &filter{accel: accel}
+}
+
+// reset starts the filter from an altitude measurement, the barometric
+// altitude includes the unknown bias
+func (f *filter) reset(h [3]float64, t time.Time, z float64, sigma float64) {
+	b := initBias * initBias
+	f.x = [3]float64{z, 0, 0}
+	f.p = [3][3]float64{
+		{sigma*sigma + h[iBias]*b, 0, -h[iBias] * b},
+		{0, initVSpeed * initVSpeed, 0},
+		{-h[iBias] * b, 0, b},
+	}
+	f.t = t
+	f.ready = true
+	f.rejects = 0
+}
+
+// predict moves the state to time t, measurements older than the
+// state are applied at the state time
+func (f *filter) predict(t time.Time) {
+	dt := t.Sub(f.t).Seconds()
+	if dt <= 0 {
+		return
+	}
+	f.t = t
+	f.x[iAlt] += f.x[iVSpeed] * dt
+
+	// P = F P F' + Q, F = [1 dt 0; 0 1 0; 0 0 1]
+	p := f.p
+	p[iAlt][iAlt] += dt*(f.p[iVSpeed][iAlt]+f.p[iAlt][iVSpeed]) + dt*dt*f.p[iVSpeed][iVSpeed]
+	p[iAlt][iVSpeed] += dt * f.p[iVSpeed][iVSpeed]
+	p[iVSpeed][iAlt] += dt * f.p[iVSpeed][iVSpeed]
+	p[iAlt][iBias] += dt * f.p[iVSpeed][iBias]
+	p[iBias][iAlt] += dt * f.p[iBias][iVSpeed]
+
+	// white noise acceleration
+	q := f.accel * f.accel
+	p[iAlt][iAlt] += q * dt * dt * dt * dt / 4
+	p[iAlt][iVSpeed] += q * dt * dt * dt / 2
+	p[iVSpeed][iAlt] += q * dt * dt * dt / 2
+	p[iVSpeed][iVSpeed] += q * dt * dt
+	p[iBias][iBias] += biasNoise * dt
+	f.p = p
+}
+
+// correct applies a measurement z = h x with variance r, returning
+// false if it was rejected
+func (f *filter) correct(h [3]float64, z float64, r float64) bool {
+	// innovation and its variance
+	var ph [3]float64
+	y := z
+	for i := range 3 {
+		y -= h[i] * f.x[i]
+		for j := range 3 {
+			ph[i] += f.p[i][j] * h[j]
+		}
+	}
+	s := r
+	for i := range 3 {
+		s += h[i] * ph[i]
+	}
+	if y*y > gate*gate*s {
+		f.rejects++
+		return false
+	}
+	f.rejects = 0
+
+	// x = x + K y, P = P - K h P, K = P h' / s
+	for i := range 3 {
+		f.x[i] += ph[i] / s * y
+	}
+	for i := range 3 {
+		for j := range 3 {
+			f.p[i][j] -= ph[i] * ph[j] / s
+		}
+	}
+	return true
+}
+
+// update applies an altitude measurement at time t with a standard
+// deviation sigma (m), restarting the filter if needed
+func (f *filter) update(h [3]float64, t time.Time, z float64, sigma float64) bool {
+	if math.IsNaN(z) || math.IsInf(z, 0) || sigma <= 0 {
+		return false
+	}
+	if !f.ready {
+		f.reset(h, t, z, sigma)
+		return true
+	}
+	f.predict(t)
+	if !f.correct(h, z, sigma*sigma) {
+		if f.rejects >= maxRejects {
+			// lost, like after a long GPS outage
+			f.reset(h, t, z, sigma)
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// UpdateGPS applies a GPS altitude (m) measured at time t, with a standard
+// deviation sigma (m). Returns false if the measurement was rejected
+func (f *filter) UpdateGPS(t time.Time, alt float64, sigma float64) bool {
+	return f.update([3]float64{1, 0, 0}, t, alt, sigma)
+}
+
+// UpdateBaro applies a barometric altitude (m) measured at time t, with a
+// standard deviation sigma (m). Returns false if the measurement was rejected
+func (f *filter) UpdateBaro(t time.Time, alt float64, sigma float64) bool {
+	return f.update([3]float64{1, 0, 1}, t, alt, sigma)
+}
+
+// Ready tells if the filter has received any measurement
+func (f *filter) Ready() bool {
+	return f.ready
+}
+
+// Altitude returns the filtered altitude (m)
+func (f *filter) Altitude() float64 {
+	return f.x[iAlt]
+}
+
+// VSpeed returns the filtered vertical speed (m/s), positive up
+func (f *filter) VSpeed() float64 {
+	return f.x[iVSpeed]
+}
+
+// Bias returns the barometric altitude bias (m)
+func (f *filter) Bias() float64 {
+	return f.x[iBias]
+}
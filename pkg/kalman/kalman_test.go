@@ -0,0 +1,79 @@
+package kalman
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// TestFlight simulates an ascent at 5 m/s and a descent at 20 m/s after the
+// burst, with noisy GPS and a biased barometer
+func TestFlight(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	f := New(0)
+	if f.Ready() {
+		t.Fatal("Filter ready without measurements")
+	}
+	const baroBias = 50.0
+	start := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	alt, vs := 500.0, 5.0
+	for s := range 1200 {
+		if s == 900 {
+			vs = -20
+		}
+		alt += vs
+		now := start.Add(time.Duration(s) * time.Second)
+		f.UpdateGPS(now, alt+rnd.NormFloat64()*10, 10)
+		f.UpdateBaro(now.Add(time.Millisecond*500), alt+0.5*vs+baroBias+rnd.NormFloat64()*2, 2)
+
+		if s == 890 || s == 1190 {
+			if math.Abs(f.VSpeed()-vs) > 0.5 {
+				t.Errorf("Vertical speed at %ds: %f, want %f", s, f.VSpeed(), vs)
+			}
+			if math.Abs(f.Altitude()-(alt+0.5*vs)) > 5 {
+				t.Errorf("Altitude at %ds: %f, want %f", s, f.Altitude(), alt+0.5*vs)
+			}
+			if math.Abs(f.Bias()-baroBias) > 5 {
+				t.Errorf("Baro bias at %ds: %f", s, f.Bias())
+			}
+		}
+	}
+}
+
+func TestBaroOnly(t *testing.T) {
+	f := New(0)
+	start := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	// GPS fix, then lost
+	f.UpdateGPS(start, 1000, 5)
+	for s := range 300 {
+		f.UpdateBaro(start.Add(time.Duration(s)*time.Second), 1000+4*float64(s), 2)
+	}
+	if math.Abs(f.VSpeed()-4) > 0.1 || math.Abs(f.Altitude()-1000-4*299) > 2 {
+		t.Errorf("Bad baro only estimate: %fm, %fm/s", f.Altitude(), f.VSpeed())
+	}
+}
+
+func TestOutliers(t *testing.T) {
+	f := New(0)
+	start := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	for s := range 60 {
+		f.UpdateGPS(start.Add(time.Duration(s)*time.Second), 1000, 5)
+	}
+	if f.UpdateGPS(start.Add(time.Minute), 6000, 5) {
+		t.Error("Outlier accepted")
+	}
+	if math.Abs(f.Altitude()-1000) > 1 {
+		t.Errorf("Altitude moved by outlier: %f", f.Altitude())
+	}
+	if f.UpdateGPS(start.Add(time.Minute), math.NaN(), 5) {
+		t.Error("NaN accepted")
+	}
+	// persistent jump, restart
+	for s := range maxRejects {
+		f.UpdateGPS(start.Add(time.Minute+time.Duration(s)*time.Second), 6000, 5)
+	}
+	if f.Altitude() != 6000 || f.VSpeed() != 0 {
+		t.Errorf("Filter not restarted: %fm, %fm/s", f.Altitude(), f.VSpeed())
+	}
+}
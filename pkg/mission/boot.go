@@ -0,0 +1,34 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// boot counter file, in the mission main directory
+const bootFile = "boot"
+
+// nextBoot increments the boot counter saved in a file and returns it,
+// the first boot is 1
+func nextBoot(path string) (int, error) {
+	boot := 0
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		boot, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || boot < 0 {
+			return 0, fmt.Errorf("Bad boot counter file %s: %q", path, data)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return 0, err
+	}
+	boot++
+	if err := replaceFile(path, []byte(strconv.Itoa(boot)+"\n")); err != nil {
+		return 0, err
+	}
+	return boot, nil
+}
@@ -0,0 +1,24 @@
+package mission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), bootFile)
+	for want := 1; want <= 3; want++ {
+		boot, err := nextBoot(path)
+		if err != nil || boot != want {
+			t.Errorf("Boot %d: %d, %v", want, boot, err)
+		}
+	}
+	os.WriteFile(path, []byte("garbage"), 0644)
+	if _, err := nextBoot(path); err == nil {
+		t.Error("Bad boot counter file accepted")
+	}
+	if _, err := nextBoot(filepath.Join(path, "no", "dir")); err == nil {
+		t.Error("Boot counter saved in a missing directory")
+	}
+}
@@ -0,0 +1,12 @@
+package mission
+
+import (
+	"syscall"
+	"time"
+)
+
+// setSystemTime sets the system clock, needs CAP_SYS_TIME (root)
+func setSystemTime(t time.Time) error {
+	tv := syscall.NsecToTimeval(t.UnixNano())
+	return syscall.Settimeofday(&tv)
+}
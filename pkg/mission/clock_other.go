@@ -0,0 +1,13 @@
+//go:build !linux
+
+package mission
+
+import (
+	"errors"
+	"time"
+)
+
+// setSystemTime is only supported on linux
+func setSystemTime(t time.Time) error {
+	return errors.New("Setting system time not supported")
+}
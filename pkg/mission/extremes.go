@@ -0,0 +1,84 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/telemetry"
+)
+
+// flight extremes file, in the mission main directory, one "name value"
+// line by extreme, NaN if unknown. Reset at the launch
+const extremesFile = "extremes"
+
+// a launch this far (m) under the saved maximum altitude is a new flight,
+// closer it's a restart during the ascent
+const restartAltMargin = 300.0
+
+// newFlight returns true if a launch at this altitude starts a new flight,
+// so the saved extremes must be reset
+func newFlight(e telemetry.Extremes, alt float64) bool {
+	return math.IsNaN(e.MaxAlt) || alt < e.MaxAlt-restartAltMargin
+}
+
+// extremes values by name in the file
+func extremesValues(e *telemetry.Extremes) map[string]*float64 {
+	return map[string]*float64{
+		"max_alt":     &e.MaxAlt,
+		"min_tin":     &e.MinTin,
+		"min_tout":    &e.MinTout,
+		"min_vbat":    &e.MinVbat,
+		"max_ascent":  &e.MaxAscent,
+		"max_descent": &e.MaxDescent,
+	}
+}
+
+// loadExtremes reads the extremes saved in a file, all of them unknown
+// if the file doesn't exist
+func loadExtremes(path string) (telemetry.Extremes, error) {
+	e := telemetry.NewExtremes()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return e, err
+	}
+	values := extremesValues(&e)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		name, value, _ := strings.Cut(line, " ")
+		if name == "max_alt_time" {
+			if e.MaxAltTime, err = time.Parse(time.RFC3339, value); err != nil {
+				return telemetry.NewExtremes(), fmt.Errorf("Bad extremes file %s: %q", path, line)
+			}
+			continue
+		}
+		v, ok := values[name]
+		if !ok {
+			return telemetry.NewExtremes(), fmt.Errorf("Bad extremes file %s: %q", path, line)
+		}
+		if *v, err = strconv.ParseFloat(value, 64); err != nil {
+			return telemetry.NewExtremes(), fmt.Errorf("Bad extremes file %s: %q", path, line)
+		}
+	}
+	return e, nil
+}
+
+// saveExtremes replaces the extremes file
+func saveExtremes(path string, e telemetry.Extremes) error {
+	var b strings.Builder
+	values := extremesValues(&e)
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(&b, "%s %s\n", name, strconv.FormatFloat(*values[name], 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, "max_alt_time %s\n", e.MaxAltTime.Format(time.RFC3339))
+	return replaceFile(path, []byte(b.String()))
+}
@@ -0,0 +1,55 @@
+package mission
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/telemetry"
+)
+
+func TestExtremesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), extremesFile)
+	e, err := loadExtremes(path)
+	if err != nil || !math.IsNaN(e.MaxAlt) || !e.MaxAltTime.IsZero() {
+		t.Errorf("Extremes without file: %+v, %v", e, err)
+	}
+
+	e = telemetry.NewExtremes()
+	e.Update(time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC), 31234.5, math.NaN(), -58.2, 3.9, 5.8)
+	if err := saveExtremes(path, e); err != nil {
+		t.Fatalf("Error saving extremes: %v", err)
+	}
+	saved, err := loadExtremes(path)
+	if err != nil {
+		t.Fatalf("Error loading extremes: %v", err)
+	}
+	if saved.MaxAlt != 31234.5 || !saved.MaxAltTime.Equal(e.MaxAltTime) || !math.IsNaN(saved.MinTin) ||
+		saved.MinTout != -58.2 || saved.MinVbat != 3.9 || saved.MaxAscent != 5.8 || !math.IsNaN(saved.MaxDescent) {
+		t.Errorf("Bad saved extremes: %+v, want %+v", saved, e)
+	}
+
+	os.WriteFile(path, []byte("max_alt high\n"), 0644)
+	if _, err := loadExtremes(path); err == nil {
+		t.Error("Bad extremes file accepted")
+	}
+	if err := saveExtremes(filepath.Join(path, "no", "dir"), e); err == nil {
+		t.Error("Extremes saved in a missing directory")
+	}
+}
+
+func TestNewFlight(t *testing.T) {
+	e := telemetry.NewExtremes()
+	if !newFlight(e, 100) {
+		t.Error("Launch without extremes not a new flight")
+	}
+	e.Update(time.Now(), 31234.5, math.NaN(), math.NaN(), math.NaN(), math.NaN())
+	if !newFlight(e, 100) {
+		t.Error("Launch from the ground not a new flight")
+	}
+	if newFlight(e, 31100) {
+		t.Error("Restart during the ascent is a new flight")
+	}
+}
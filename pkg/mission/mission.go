@@ -2,53 +2,112 @@ package mission
 
 import (
 	"fmt"
+	"math"
+	"os"
 	"time"
 
+	"github.com/ladecadence/EkiGo/pkg/atmosphere"
 	"github.com/ladecadence/EkiGo/pkg/batt"
 	"github.com/ladecadence/EkiGo/pkg/config"
 	"github.com/ladecadence/EkiGo/pkg/ds18b20"
 	"github.com/ladecadence/EkiGo/pkg/gps"
+	"github.com/ladecadence/EkiGo/pkg/kalman"
 	"github.com/ladecadence/EkiGo/pkg/led"
 	"github.com/ladecadence/EkiGo/pkg/logging"
 	"github.com/ladecadence/EkiGo/pkg/mcp3002"
 	"github.com/ladecadence/EkiGo/pkg/ms5607"
 	"github.com/ladecadence/EkiGo/pkg/picture"
+	"github.com/ladecadence/EkiGo/pkg/position"
 	"github.com/ladecadence/EkiGo/pkg/pwrsel"
 	"github.com/ladecadence/EkiGo/pkg/rf95"
 	"github.com/ladecadence/EkiGo/pkg/ssdv"
 	"github.com/ladecadence/EkiGo/pkg/telemetry"
 )
 
+const (
+	// system time synchronization defaults
+	defaultTimeSyncThreshold = time.Second * 2
+	defaultTimeSyncInterval  = time.Minute * 10
+	minGpsYear               = 2020
+	// APRS telemetry definitions are sent every this number of packets
+	aprsDefinitionsEvery = 20
+	// default flight summary rate, in packets
+	defaultSummaryEvery = 10
+	// minimum time between writes of the changed flight extremes
+	extremesSaveInterval = time.Minute
+	// altitude standard deviation of fixes without vertical accuracy (m)
+	gpsAltSigma = 15.0
+	// barometer pressure noise (mbar) and minimum altitude deviation (m)
+	baroPresNoise = 0.05
+	baroMinSigma  = 1.0
+)
+
 type Mission interface {
 	Gps() gps.GPS
+	Pps() gps.PPS
+	Vertical() kalman.Filter
+	Phase() Phase
+	PhaseConfig() config.PhaseConfig
 	Log() logging.Logging
 	DataLog() logging.Logging
+	WriteDataLog() error
 	UpdateTelemetry(config.Config) error
 	SendTelemetry() error
 	SendSSDV(config.Config) error
 	Telemetry() telemetry.Telemetry
-	SetTimeGPS(int, int, int) error
+	SetTimeGPS() error
+}
+
+// thermometer is a temperature sensor, like the DS18B20
+type thermometer interface {
+	Read() (float64, error)
 }
 
 type mission struct {
+	id            string
 	log           logging.Logging
 	dataLog       logging.Logging
 	gps           gps.GPS
+	pps           gps.PPS
 	led           led.LED
 	adc           mcp3002.MCP3002
 	batt          batt.Batt
 	baro          ms5607.MS5607
-	temp_internal ds18b20.DS18B20
-	temp_external ds18b20.DS18B20
+	atmo          atmosphere.Atmosphere
+	atmoCalibrate bool // calibrate the sea level pressure with the first good fix
+	baroFallback  bool
+	vertical      kalman.Filter
+	lastGpsFix    time.Time // last fix applied to the vertical filter
+	phase         phaseDetector
+	phaseConf     []config.PhaseConfig // by phase, with the global values
+	wind          windProfile
+	loraLowPwr    uint8
+	loraHighPwr   uint8
+	temp_internal thermometer
+	temp_external thermometer
 	lora          rf95.RF95
 	telem         telemetry.Telemetry
+	telemFormat   string
+	datalogJson   bool
+	aprsPath      []string
+	packets       int
+	extremes      telemetry.Extremes
+	extremesPath  string
+	extremesDirty bool      // changed since the last save
+	extremesSaved time.Time // last save
+	summaryEvery  int       // 0 never
 	pic           picture.Picture
 	ssdv          ssdv.SSDV
 	pwrSel        pwrsel.Pwrsel
+	simulated     bool // replaying a flight, without hardware
+	// system time synchronization
+	timeSyncThreshold time.Duration
+	timeSyncInterval  time.Duration
+	lastTimeSync      time.Time
 }
 
 func New(conf config.Config) (Mission, error) {
-	mission := mission{}
+	mission := mission{id: conf.ID()}
 
 	// log
 	var err error
@@ -65,53 +124,214 @@ func New(conf config.Config) (Mission, error) {
 	}
 
 	// gps
-	mission.gps, err = gps.New(conf.GpsPort(), conf.GpsSpeed())
+	model, err := gps.ParseDynModel(conf.GpsDynModel())
 	if err != nil {
 		return nil, err
 	}
-
-	// status led
-	mission.led, err = led.New(conf.LedPin())
+	switch conf.GpsSource() {
+	case "", "serial":
+		mission.gps, err = gps.New(conf.GpsPort(), conf.GpsSpeed(), model, conf.GpsAutobaud())
+	case "replay":
+		speed := conf.GpsReplaySpeed()
+		if speed == 0 {
+			speed = 1
+		}
+		mission.gps, err = gps.NewReplay(conf.GpsReplayFile(), speed, conf.GpsReplayLoop())
+		mission.log.Log(logging.LogWarn, fmt.Sprintf("Replaying GPS data from %s", conf.GpsReplayFile()))
+	case "gpsd":
+		mission.gps, err = gps.NewGpsd(conf.GpsdAddr())
+	default:
+		err = fmt.Errorf("Unknown GPS source: %s", conf.GpsSource())
+	}
 	if err != nil {
 		return nil, err
 	}
-
-	// ADC and battery
-	mission.adc, err = mcp3002.New(conf.ADCCsPin(), conf.ADCChan())
+	policy := gps.DefaultPolicy
+	if conf.GpsMinSats() > 0 {
+		policy.MinSats = conf.GpsMinSats()
+	}
+	policy.MinFixType, err = gps.ParseFixType(conf.GpsMinFix())
 	if err != nil {
 		return nil, err
 	}
+	policy.MaxHDOP = conf.GpsMaxHDOP()
+	policy.RequireActive = conf.GpsRequireActive()
+	mission.gps.SetPolicy(policy)
+	filter := gps.DefaultFilter
+	if conf.GpsMaxHSpeed() > 0 {
+		filter.MaxHSpeed = conf.GpsMaxHSpeed()
+	}
+	if conf.GpsMaxVSpeed() > 0 {
+		filter.MaxVSpeed = conf.GpsMaxVSpeed()
+	}
+	if conf.GpsMinAlt() != 0 || conf.GpsMaxAlt() != 0 {
+		filter.MinAlt = conf.GpsMinAlt()
+		filter.MaxAlt = conf.GpsMaxAlt()
+	}
+	mission.gps.SetFilter(filter)
 
-	mission.batt, err = batt.New(conf.BattEnablePin(), conf.ADCVMult(), conf.ADCVDivider())
-	if err != nil {
-		return nil, err
+	// PPS
+	if conf.PpsPin() != 0 {
+		mission.pps, err = gps.NewPPS(conf.PpsPin())
+		if err != nil {
+			return nil, err
+		}
+		mission.gps.SetPPS(mission.pps)
 	}
 
-	// barometer
-	mission.baro, err = ms5607.New(conf.BaroI2CBus(), conf.BaroI2CAddr())
-	if err != nil {
-		return nil, err
+	// time synchronization
+	mission.timeSyncThreshold = defaultTimeSyncThreshold
+	if conf.TimeSyncThreshold() > 0 {
+		mission.timeSyncThreshold = time.Duration(conf.TimeSyncThreshold() * float64(time.Second))
+	}
+	mission.timeSyncInterval = defaultTimeSyncInterval
+	if conf.TimeSyncInterval() != 0 {
+		mission.timeSyncInterval = time.Duration(conf.TimeSyncInterval()) * time.Second
 	}
 
-	// temperature sensors
-	mission.temp_internal = ds18b20.DS18B20{}
-	mission.temp_internal.Init(conf.TempInternalAddr())
-	mission.temp_external = ds18b20.DS18B20{}
-	mission.temp_external.Init(conf.TempExternalAddr())
+	// sensors and radio, simulated when replaying a flight on a desktop
+	if conf.GpsSource() == "replay" {
+		mission.simulateHardware()
+	} else {
+		err = mission.openHardware(conf)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// LoRa radio
-	mission.lora, err = rf95.New(conf.LoraSPIChannel(), conf.LoraCSPin(), conf.LoraIntPin(), false)
-	if err != nil {
-		return nil, err
+	mission.atmo = atmosphere.Standard()
+	if conf.BaroSeaLevel() > 0 {
+		mission.atmo.SeaLevel = conf.BaroSeaLevel()
+	} else {
+		mission.atmoCalibrate = true
+	}
+	mission.baroFallback = conf.BaroAltFallback()
+	mission.vertical = kalman.New(0)
+
+	// flight phases
+	mission.phase = newPhaseDetector()
+	mission.wind = newWindProfile()
+	mission.phaseConf = make([]config.PhaseConfig, len(phaseNames))
+	for name, pc := range conf.Phases() {
+		p, err := ParsePhase(name)
+		if err != nil {
+			return nil, err
+		}
+		if pc.LoraPwr != "" && pc.LoraPwr != "low" && pc.LoraPwr != "high" {
+			return nil, fmt.Errorf("Unknown LoRa power for phase %s: %s", name, pc.LoraPwr)
+		}
+		mission.phaseConf[p] = pc
+	}
+	for i := range mission.phaseConf {
+		if mission.phaseConf[i].PacketRepeat == 0 {
+			mission.phaseConf[i].PacketRepeat = conf.PacketRepeat()
+		}
+		if mission.phaseConf[i].PacketDelay == 0 {
+			mission.phaseConf[i].PacketDelay = conf.PacketDelay()
+		}
 	}
-	mission.lora.SetFrequency(conf.LoraFreq())
 
 	// power selection
 	// TODO read power selection pin
-	mission.lora.SetTxPower(conf.LoraLowPwr())
+	mission.loraLowPwr = conf.LoraLowPwr()
+	mission.loraHighPwr = conf.LoraHighPwr()
+	mission.setTxPower()
 
 	// telemetry
 	mission.telem = telemetry.New(conf.ID(), conf.Msg(), conf.Separator())
+	err = mission.telem.Register(telemetry.Field{Name: "balt", Key: "BA", Unit: "m",
+		Type: telemetry.FloatField, Precision: 1})
+	if err != nil {
+		return nil, err
+	}
+	err = mission.telem.Register(telemetry.Field{Name: "falt", Key: "FA", Unit: "m",
+		Type: telemetry.FloatField, Precision: 1})
+	if err != nil {
+		return nil, err
+	}
+	err = mission.telem.Register(telemetry.Field{Name: "phase", Key: "PH", Type: telemetry.StringField})
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range []telemetry.Field{
+		{Name: "pred_lat", Key: "PLAT", Unit: "deg", Type: telemetry.FloatField, Precision: 5},
+		{Name: "pred_lon", Key: "PLON", Unit: "deg", Type: telemetry.FloatField, Precision: 5},
+	} {
+		err = mission.telem.Register(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	mission.telem.Set("phase", mission.Phase().String())
+
+	// boot counter, 0 if unknown
+	boot, err := nextBoot(conf.PathMainDir() + bootFile)
+	if err != nil {
+		mission.log.Log(logging.LogError, fmt.Sprintf("Error updating the boot counter: %v", err))
+	}
+	mission.telem.SetBoot(boot)
+	mission.log.Log(logging.LogInfo, fmt.Sprintf("Boot number %d", boot))
+
+	// flight extremes, kept across restarts
+	mission.extremesPath = conf.PathMainDir() + extremesFile
+	mission.extremes, err = loadExtremes(mission.extremesPath)
+	if err != nil {
+		mission.log.Log(logging.LogError, fmt.Sprintf("Error loading the flight extremes: %v", err))
+	}
+	switch {
+	case conf.SummaryEvery() == 0:
+		mission.summaryEvery = defaultSummaryEvery
+	case conf.SummaryEvery() > 0:
+		mission.summaryEvery = conf.SummaryEvery()
+	}
+	switch conf.TelemetryFormat() {
+	case "", "aprs":
+		mission.telemFormat = "aprs"
+	case "ukhas", "binary", "lora-aprs", "ax25":
+		mission.telemFormat = conf.TelemetryFormat()
+	default:
+		return nil, fmt.Errorf("Unknown telemetry format: %s", conf.TelemetryFormat())
+	}
+	if len(conf.AprsFields()) > 0 {
+		err = mission.telem.SetAprsFields(conf.AprsFields())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(conf.CsvFields()) > 0 {
+		err = mission.telem.SetCsvFields(conf.CsvFields())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// datalog schema
+	switch conf.DatalogFormat() {
+	case "", "csv":
+		err = writeSchema(mission.dataLog, mission.telem.CsvSchema(), mission.telem.CsvHeader())
+	case "jsonl":
+		mission.datalogJson = true
+		err = writeSchema(mission.dataLog, mission.telem.JsonSchema())
+	default:
+		err = fmt.Errorf("Unknown datalog format: %s", conf.DatalogFormat())
+	}
+	if err != nil {
+		return nil, err
+	}
+	mission.aprsPath = conf.AprsPath()
+	if mission.telemFormat == "ax25" {
+		// check the addresses
+		_, err = telemetry.EncodeAX25(conf.ID(), mission.aprsPath, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(conf.UkhasFields()) > 0 {
+		err = mission.telem.SetUkhasFields(conf.UkhasFields())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// picture
 	mission.pic = picture.New(0, conf.ID(), conf.PathMainDir()+conf.PathImgDir())
@@ -125,19 +345,121 @@ func New(conf config.Config) (Mission, error) {
 		mission.pic.Number,
 	)
 
-	// pwr selection pin
-	mission.pwrSel, err = pwrsel.New(conf.PwrPin())
+	return &mission, nil
+}
+
+// writeSchema writes the schema lines of a datalog if it's empty, not
+// again in the middle of an appended file
+func writeSchema(l logging.Logging, lines ...string) error {
+	info, err := os.Stat(l.Filename())
+	if err != nil || info.Size() > 0 {
+		return err
+	}
+	for _, line := range lines {
+		if err := l.Log(logging.LogClean, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openHardware opens the sensors, the LoRa radio and the GPIOs
+func (m *mission) openHardware(conf config.Config) error {
+	// status led
+	var err error
+	m.led, err = led.New(conf.LedPin())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &mission, nil
+	// ADC and battery
+	m.adc, err = mcp3002.New(conf.ADCCsPin(), conf.ADCChan())
+	if err != nil {
+		return err
+	}
+
+	m.batt, err = batt.New(conf.BattEnablePin(), conf.ADCVMult(), conf.ADCVDivider())
+	if err != nil {
+		return err
+	}
+
+	// barometer
+	m.baro, err = ms5607.New(conf.BaroI2CBus(), conf.BaroI2CAddr())
+	if err != nil {
+		return err
+	}
+
+	// temperature sensors
+	tin := &ds18b20.DS18B20{}
+	tin.Init(conf.TempInternalAddr())
+	m.temp_internal = tin
+	tout := &ds18b20.DS18B20{}
+	tout.Init(conf.TempExternalAddr())
+	m.temp_external = tout
+
+	// LoRa radio
+	m.lora, err = rf95.New(conf.LoraSPIChannel(), conf.LoraCSPin(), conf.LoraIntPin(), false)
+	if err != nil {
+		return err
+	}
+	m.lora.SetFrequency(conf.LoraFreq())
+
+	// pwr selection pin
+	m.pwrSel, err = pwrsel.New(conf.PwrPin())
+	return err
 }
 
 func (m *mission) Gps() gps.GPS {
 	return m.gps
 }
 
+// Pps returns the GPS PPS input, nil if not configured
+func (m *mission) Pps() gps.PPS {
+	return m.pps
+}
+
+// Vertical returns the filtered altitude and vertical speed
+func (m *mission) Vertical() kalman.Filter {
+	return m.vertical
+}
+
+// Phase returns the current flight phase
+func (m *mission) Phase() Phase {
+	return m.phase.phase
+}
+
+// PhaseConfig returns the configuration of the current flight phase
+func (m *mission) PhaseConfig() config.PhaseConfig {
+	return m.phaseConf[m.phase.phase]
+}
+
+// predictLanding updates the landing prediction from the last good
+// position and the filtered altitude
+func (m *mission) predictLanding(pos position.Position) {
+	ground := m.phase.ground
+	if math.IsNaN(ground) {
+		ground = 0
+	}
+	pos.Alt = m.vertical.Altitude()
+	landing, eta, ok := predictLanding(pos, m.vertical.VSpeed(), ground, &m.wind)
+	if !ok {
+		return
+	}
+	m.log.Log(logging.LogData, fmt.Sprintf("Landing prediction: %s, in %v, %.0fm away",
+		landing.Decimal(), eta.Round(time.Second), pos.Distance(landing)))
+	m.telem.Set("pred_lat", landing.Lat)
+	m.telem.Set("pred_lon", landing.Lon)
+}
+
+// setTxPower sets the LoRa power of the current flight phase
+func (m *mission) setTxPower() {
+	if m.PhaseConfig().LoraPwr == "high" {
+		m.lora.SetTxPower(m.loraHighPwr)
+	} else {
+		m.lora.SetTxPower(m.loraLowPwr)
+	}
+}
+
 func (m *mission) Log() logging.Logging {
 	return m.log
 }
@@ -146,92 +468,353 @@ func (m *mission) DataLog() logging.Logging {
 	return m.dataLog
 }
 
+// WriteDataLog writes the telemetry to the datalog in the configured format
+func (m *mission) WriteDataLog() error {
+	if m.datalogJson {
+		return m.dataLog.Log(logging.LogClean, m.telem.JsonString())
+	}
+	return m.dataLog.Log(logging.LogClean, m.telem.CsvString())
+}
+
 func (m *mission) Telemetry() telemetry.Telemetry {
 	return m.telem
 }
 
-func (m *mission) SetTimeGPS(hour, minute, second int) error {
+// SetTimeGPS sets the system clock from the GPS time if they
+// differ more than the configured threshold
+func (m *mission) SetTimeGPS() error {
+	// the replayed flight is not now
+	if m.simulated {
+		return nil
+	}
+	gpsTime, err := gpsDateTime(m.gps)
+	if err != nil {
+		return err
+	}
+	m.lastTimeSync = time.Now()
+
+	// GPS time now, adding the time passed since the start of the fix
+	// second (PPS pulse) or since the fix was received
+	fix := m.gps.Fix()
+	source := "NMEA"
+	now := gpsTime.Add(time.Since(fix.Received))
+	if !fix.PPS.IsZero() {
+		source = "PPS"
+		now = gpsTime.Add(time.Since(fix.PPS))
+	}
+	drift := time.Until(now)
+	if drift.Abs() < m.timeSyncThreshold {
+		return nil
+	}
+	err = setSystemTime(now)
+	if err != nil {
+		return err
+	}
+	m.log.Log(logging.LogInfo, fmt.Sprintf("System time set to %s from %s, corrected by %v",
+		now.Format(time.RFC3339), source, drift.Round(time.Millisecond)))
 	return nil
 }
 
+// gpsDateTime returns the UTC date and time of the current GPS fix
+func gpsDateTime(g gps.GPS) (time.Time, error) {
+	hour, minute, second, err := g.Hms()
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, month, year, err := g.Dmy()
+	if err != nil {
+		return time.Time{}, err
+	}
+	// receivers without almanac can send old dates
+	if year < minGpsYear {
+		return time.Time{}, fmt.Errorf("Invalid GPS date year: %d", year)
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC), nil
+}
+
 func (m *mission) UpdateTelemetry(conf config.Config) error {
 	// Update sensor data
 	// GPS
 	err := m.gps.Update()
+	for _, e := range m.gps.Errors() {
+		m.log.Log(logging.LogWarn, fmt.Sprintf("GPS: %v", e))
+	}
+	hAcc, vAcc := m.gps.Accuracy()
+	m.log.Log(logging.LogData,
+		fmt.Sprintf("GPS health: %v, fix: %v, Quality: %d, Status: %s, HDOP: %.2f, VDOP: %.2f, PDOP: %.2f, Acc: %.1fm/%.1fm",
+			m.gps.Health(),
+			m.gps.FixType(),
+			m.gps.Quality(),
+			m.gps.Status(),
+			m.gps.HDOP(),
+			m.gps.VDOP(),
+			m.gps.PDOP(),
+			hAcc,
+			vAcc,
+		),
+	)
+	if m.pps != nil {
+		m.log.Log(logging.LogData, fmt.Sprintf("PPS locked: %v, jitter: %v",
+			m.pps.Locked(), m.pps.Jitter().Round(time.Microsecond)))
+	}
+	if sats := m.gps.Satellites(); len(sats) > 0 {
+		m.log.Log(logging.LogData, "GPS sats: "+gps.SatellitesString(sats))
+	}
+	// without a good fix, keep going with the last good position
 	if err != nil {
-		return err
+		m.log.Log(logging.LogWarn, fmt.Sprintf("GPS update: %v", err))
 	}
+	pos, age := m.gps.LastGood()
 	m.log.Log(logging.LogData,
-		fmt.Sprintf("%f%s, %f%s, Alt: %.1fm, Sats: %d, Date: %s, Time: %s",
-			gps.NmeaToDec(m.gps.Lat()),
-			m.gps.NS(),
-			gps.NmeaToDec(m.gps.Lon()),
-			m.gps.EW(),
-			m.gps.Alt(),
+		fmt.Sprintf("%v, Sats: %d, Date: %s, Time: %s, State: %v, Age: %v",
+			pos,
 			m.gps.Sats(),
 			m.Gps().Date(),
 			m.Gps().Time(),
+			m.gps.State(),
+			age.Round(time.Millisecond),
 		),
 	)
 
-	// baro
-	err = m.baro.Update()
-	if err != nil {
-		return err
+	// new fixes to the vertical filter
+	if fix := m.gps.Fix(); m.gps.State() == gps.FixStateCurrent && fix.Received != m.lastGpsFix {
+		m.lastGpsFix = fix.Received
+		sigma := vAcc
+		if sigma <= 0 {
+			sigma = gpsAltSigma
+		}
+		if !m.vertical.UpdateGPS(fix.Received, pos.Alt, sigma) {
+			m.log.Log(logging.LogWarn, fmt.Sprintf("GPS altitude rejected by the vertical filter: %.1fm", pos.Alt))
+		}
+	}
+
+	// resync system time
+	if m.timeSyncInterval > 0 && time.Since(m.lastTimeSync) > m.timeSyncInterval {
+		if err := m.SetTimeGPS(); err != nil {
+			m.log.Log(logging.LogError, fmt.Sprintf("Error setting system time: %v", err))
+		}
+	}
+
+	// baro, NaN if it fails
+	pres := math.NaN()
+	if err := m.baro.Update(); err != nil {
+		m.log.Log(logging.LogError, fmt.Sprintf("Error reading barometer: %v", err))
+	} else {
+		pres = m.baro.GetPres()
+	}
+	if m.atmoCalibrate && m.gps.State() == gps.FixStateCurrent && !math.IsNaN(pres) {
+		m.atmo.Calibrate(pres, pos.Alt)
+		m.atmoCalibrate = false
+		m.log.Log(logging.LogInfo, fmt.Sprintf("Barometer calibrated at %.1fm, sea level pressure: %.2f mbar",
+			pos.Alt, m.atmo.SeaLevel))
+	}
+	baroAlt := m.atmo.Altitude(pres)
+	m.log.Log(logging.LogData, fmt.Sprintf("BARO: %f, Alt: %.1fm", pres, baroAlt))
+	// altitude change of the pressure noise, bigger with the altitude
+	baroSigma := math.Max(baroMinSigma, math.Abs(m.atmo.Altitude(pres-baroPresNoise)-baroAlt))
+	if !math.IsNaN(baroAlt) && !m.vertical.UpdateBaro(time.Now(), baroAlt, baroSigma) {
+		m.log.Log(logging.LogWarn, fmt.Sprintf("Barometric altitude rejected by the vertical filter: %.1fm", baroAlt))
+	}
+	phaseChanged := false
+	if m.vertical.Ready() {
+		m.log.Log(logging.LogData, fmt.Sprintf("VERTICAL: Alt: %.1fm, Speed: %.2fm/s, Baro bias: %.1fm",
+			m.vertical.Altitude(), m.vertical.VSpeed(), m.vertical.Bias()))
+
+		// flight phase
+		old := m.phase.phase
+		if m.phase.update(time.Now(), m.vertical.Altitude(), m.vertical.VSpeed()) {
+			phaseChanged = true
+			m.log.Log(logging.LogInfo, fmt.Sprintf("Flight phase %v -> %v at %.1fm, %.2fm/s",
+				old, m.phase.phase, m.vertical.Altitude(), m.vertical.VSpeed()))
+			m.setTxPower()
+			if old == PhasePrelaunch && m.phase.phase == PhaseLaunch && newFlight(m.extremes, m.vertical.Altitude()) {
+				m.resetExtremes()
+			}
+		}
+
+		// wind profile while ascending, landing prediction while descending
+		switch m.phase.phase {
+		case PhaseLaunch, PhaseAscent, PhaseFloat:
+			if m.gps.State() == gps.FixStateCurrent {
+				m.wind.add(pos.Alt, m.gps.Hdg(), m.gps.Spd())
+			}
+		case PhaseBurst, PhaseDescent:
+			m.predictLanding(pos)
+		}
 	}
-	m.log.Log(logging.LogData, fmt.Sprintf("BARO: %f", m.baro.GetPres()))
 
-	// temperatures
+	// temperatures, NaN if they fail
 	tin, err := m.temp_internal.Read()
 	if err != nil {
-		return err
+		m.log.Log(logging.LogError, fmt.Sprintf("Error reading internal temperature: %v", err))
+		tin = math.NaN()
 	}
 	m.log.Log(logging.LogData, fmt.Sprintf("TIN: %.2f", tin))
 
 	tout, err := m.temp_external.Read()
 	if err != nil {
-		return err
+		m.log.Log(logging.LogError, fmt.Sprintf("Error reading external temperature: %v", err))
+		tout = math.NaN()
 	}
 	m.log.Log(logging.LogData, fmt.Sprintf("TOUT: %.2f", tout))
 
 	// Battery
 	vBatt, err := m.batt.Read(m.adc, uint8(conf.ADCChan()))
 	if err != nil {
-		return err
+		m.log.Log(logging.LogError, fmt.Sprintf("Error reading battery: %v", err))
+		vBatt = math.NaN()
 	}
 	m.log.Log(logging.LogData, fmt.Sprintf("VBATT: %.1f", vBatt))
 
+	// power of the flight phase, or the power selection pin
 	pwrSel := m.pwrSel.Read()
+	if pwr := m.PhaseConfig().LoraPwr; pwr != "" {
+		pwrSel = pwr == "high"
+	}
+
+	// filtered barometric altitude without a current fix
+	telemPos := m.gps.Position()
+	baroAltFallback := m.baroFallback && m.gps.State() != gps.FixStateCurrent && m.vertical.Ready()
+	if baroAltFallback {
+		telemPos.Alt = m.vertical.Altitude()
+		m.log.Log(logging.LogWarn, fmt.Sprintf("No current GPS fix, using barometric altitude: %.1fm", telemPos.Alt))
+	}
+	arate := math.NaN()
+	if m.vertical.Ready() {
+		arate = m.vertical.VSpeed()
+	}
 
 	// Create telemetry packet
 	m.Telemetry().Update(
-		m.gps.Lat(),
-		m.gps.NS(),
-		m.gps.Lon(),
-		m.gps.EW(),
-		m.gps.Alt(),
+		telemPos,
 		m.gps.Hdg(),
 		m.gps.Spd(),
 		m.gps.Sats(),
+		m.gps.Health().String(),
 		vBatt,
-		m.baro.GetPres(),
+		pres,
 		tin,
 		tout,
 		pwrSel)
 
+	// GPS values held from an older fix are stale, without a fix invalid
+	gpsValidity := telemetry.Valid
+	switch m.gps.State() {
+	case gps.FixStateNone:
+		gpsValidity = telemetry.Invalid
+	case gps.FixStateHeld, gps.FixStateRejected:
+		gpsValidity = telemetry.Stale
+	}
+	m.telem.SetValidity(gpsValidity, "pos", "gps", "hdg", "spd")
+	if !baroAltFallback {
+		m.telem.SetValidity(gpsValidity, "alt")
+	}
+
+	// invalid without barometer or filter
+	m.telem.Set("balt", baroAlt)
+	falt := math.NaN()
+	if m.vertical.Ready() {
+		falt = m.vertical.Altitude()
+	}
+	m.telem.Set("falt", falt)
+	m.telem.Set("arate", arate)
+	m.telem.Set("phase", m.phase.phase.String())
+
+	// flight extremes, with the valid altitude
+	extremeAlt := telemPos.Alt
+	if m.telem.Validity("alt") != telemetry.Valid {
+		extremeAlt = math.NaN()
+	}
+	if m.extremes.Update(time.Now(), extremeAlt, tin, tout, vBatt, arate) {
+		m.extremesDirty = true
+	}
+	// saved every extremesSaveInterval to spare the SD card, and at the
+	// phase changes, like the landing
+	if m.extremesDirty && (phaseChanged || time.Since(m.extremesSaved) >= extremesSaveInterval) {
+		m.writeExtremes()
+	}
+
 	return nil
 }
 
+// resetExtremes starts the extremes of a new flight
+func (m *mission) resetExtremes() {
+	m.extremes = telemetry.NewExtremes()
+	m.writeExtremes()
+	m.log.Log(logging.LogInfo, "New flight, extremes reset")
+}
+
+// writeExtremes saves the flight extremes file
+func (m *mission) writeExtremes() {
+	if err := saveExtremes(m.extremesPath, m.extremes); err != nil {
+		m.log.Log(logging.LogError, fmt.Sprintf("Error saving the flight extremes: %v", err))
+		return
+	}
+	m.extremesDirty = false
+	m.extremesSaved = time.Now()
+}
+
+// telemetryPackets returns the packets to send in the configured format,
+// and the flight summary every summaryEvery packets
+func (m *mission) telemetryPackets() ([][]uint8, error) {
+	defer func() { m.packets++ }()
+	summary := m.summaryEvery > 0 && m.packets%m.summaryEvery == 0
+	var packets [][]uint8
+	var sum []uint8 // the flight summary in the same format
+	switch m.telemFormat {
+	case "ukhas":
+		packets = [][]uint8{[]uint8(m.telem.UkhasString())}
+		sum = []uint8(m.telem.UkhasSummary(m.extremes))
+	case "binary":
+		packets = [][]uint8{m.telem.Binary()}
+		sum = m.telem.BinarySummary(m.extremes)
+	case "lora-aprs", "ax25":
+		infos := []string{m.telem.AprsPosition(), m.telem.AprsTelemetry()}
+		if m.packets%aprsDefinitionsEvery == 0 {
+			infos = append(infos, m.telem.AprsDefinitions()...)
+		}
+		if summary {
+			infos = append(infos, m.telem.AprsSummary(m.extremes))
+		}
+		// APRS packets, with the summary as a status report
+		for _, info := range infos {
+			if m.telemFormat == "lora-aprs" {
+				packets = append(packets, telemetry.LoraAprs(m.id, m.aprsPath, info))
+				continue
+			}
+			frame, err := telemetry.EncodeAX25(m.id, m.aprsPath, info)
+			if err != nil {
+				return nil, err
+			}
+			packets = append(packets, frame)
+		}
+		return packets, nil
+	default:
+		packets = [][]uint8{[]uint8(m.telem.AprsString())}
+		sum = []uint8(m.telem.SummaryString(m.extremes))
+	}
+	if summary {
+		packets = append(packets, sum)
+	}
+	return packets, nil
+}
+
 func (m *mission) SendTelemetry() error {
 	err := m.log.Log(logging.LogInfo, "Sending telemetry packet...")
 	if err != nil {
 		return err
 	}
-	err = m.lora.Send([]uint8(m.telem.AprsString()))
+	packets, err := m.telemetryPackets()
 	if err != nil {
 		return err
 	}
-	m.lora.WaitPacketSent()
+	for _, packet := range packets {
+		err = m.lora.Send(packet)
+		if err != nil {
+			return err
+		}
+		m.lora.WaitPacketSent()
+	}
 	err = m.log.Log(logging.LogInfo, "Telemetry packet sent.")
 	if err != nil {
 		return err
@@ -244,6 +827,10 @@ func (m *mission) SendTelemetry() error {
 }
 
 func (m *mission) SendSSDV(conf config.Config) error {
+	if m.simulated {
+		m.log.Log(logging.LogInfo, "No camera when simulating, SSDV skipped")
+		return nil
+	}
 	err := m.pic.Capture(true)
 	if err != nil {
 		m.log.Log(logging.LogError, fmt.Sprintf("Error taking picture: %v", err))
@@ -264,13 +851,7 @@ func (m *mission) SendSSDV(conf config.Config) error {
 		conf.ID(),
 		conf.SubID(),
 		conf.Msg(),
-		fmt.Sprintf("%f%s, %f%s, %.1fm",
-			gps.NmeaToDec(m.gps.Lat()),
-			m.gps.NS(),
-			gps.NmeaToDec(m.gps.Lon()),
-			m.gps.EW(),
-			m.gps.Alt(),
-		),
+		m.gps.Position(),
 	)
 	if err != nil {
 		m.log.Log(logging.LogError, fmt.Sprintf("Error adding info to SSDV picture: %v", err))
@@ -319,7 +900,7 @@ func (m *mission) SendSSDV(conf config.Config) error {
 		}
 
 		// check if we need to send telemetry between image packets
-		if timeDiff := time.Now().Sub(lastTime); timeDiff > time.Second*time.Duration(conf.PacketDelay()) {
+		if timeDiff := time.Now().Sub(lastTime); timeDiff > time.Second*time.Duration(m.PhaseConfig().PacketDelay) {
 			err := m.UpdateTelemetry(conf)
 			if err != nil {
 				return err
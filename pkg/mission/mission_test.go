@@ -0,0 +1,104 @@
+package mission
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/atmosphere"
+	"github.com/ladecadence/EkiGo/pkg/config"
+	"github.com/ladecadence/EkiGo/pkg/gps"
+	"github.com/ladecadence/EkiGo/pkg/logging"
+	"github.com/ladecadence/EkiGo/pkg/telemetry"
+)
+
+func TestWriteSchema(t *testing.T) {
+	l, err := logging.New(filepath.Join(t.TempDir(), "datalog_"))
+	if err != nil {
+		t.Fatalf("Error creating datalog: %v", err)
+	}
+	if err := writeSchema(l, "# schema", "a,b"); err != nil {
+		t.Fatalf("Error writing schema: %v", err)
+	}
+	l.Log(logging.LogClean, "1,2")
+	// appending to the datalog
+	if err := writeSchema(l, "# schema", "a,b"); err != nil {
+		t.Fatalf("Error writing schema: %v", err)
+	}
+	data, err := os.ReadFile(l.Filename())
+	if err != nil || string(data) != "# schema\na,b\n1,2\n" {
+		t.Errorf("Bad datalog: %q, %v", data, err)
+	}
+}
+
+// fixedBaro reads a constant pressure
+type fixedBaro struct{ pres float64 }
+
+func (fixedBaro) ReadProm() error              { return nil }
+func (fixedBaro) ReadADC(uint8) (int64, error) { return 0, nil }
+func (fixedBaro) Update() error                { return nil }
+func (fixedBaro) GetTemp() float64             { return 20 }
+func (b fixedBaro) GetPres() float64           { return b.pres }
+
+func TestBaroAltFallback(t *testing.T) {
+	// replayed fixes above 545m, over the GPS altitude bounds
+	dir := t.TempDir()
+	data, err := os.ReadFile("../../testdata/testconfig.toml")
+	if err != nil {
+		t.Fatalf("Error reading config: %v", err)
+	}
+	toml := strings.NewReplacer(
+		"gps_source = 'serial'", "gps_source = 'replay'",
+		"gps_replay_file = 'testdata/flight.nmea'", "gps_replay_file = '../../testdata/flight.nmea'",
+		"gps_replay_speed = 1.0", "gps_replay_speed = 100.0",
+		"gps_max_alt = 60000.0", "gps_max_alt = 500.0",
+		"path_main_dir = '/home/pi/eki2/'", "path_main_dir = '"+dir+"/'",
+	).Replace(string(data))
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(toml), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	conf, err := config.GetConfig(filepath.Join(dir, "config.toml"))
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	mis, err := New(conf)
+	if err != nil {
+		t.Fatalf("Error creating mission: %v", err)
+	}
+	m := mis.(*mission)
+	defer m.gps.Close()
+	m.baro = fixedBaro{atmosphere.Pressure(400)}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for err := m.gps.Update(); !errors.Is(err, gps.ErrFixRejected); err = m.gps.Update() {
+		if time.Now().After(deadline) {
+			t.Fatalf("No fix rejected: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for range 3 {
+		if err := m.UpdateTelemetry(conf); err != nil {
+			t.Fatalf("Error updating telemetry: %v", err)
+		}
+	}
+	if m.gps.State() == gps.FixStateCurrent {
+		t.Fatalf("Fix above gps_max_alt not rejected: %v", m.gps.State())
+	}
+	var record struct {
+		Alt *float64 `json:"alt"`
+	}
+	if err := json.Unmarshal([]byte(m.telem.JsonString()), &record); err != nil {
+		t.Fatalf("Error decoding telemetry: %v", err)
+	}
+	if record.Alt == nil || math.Abs(*record.Alt-400) > 1 {
+		t.Errorf("Bad fallback altitude: %v", record.Alt)
+	}
+	if v := m.telem.Validity("alt"); v != telemetry.Valid {
+		t.Errorf("Bad fallback altitude validity: %v", v)
+	}
+}
@@ -0,0 +1,150 @@
+package mission
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Flight phase detection from the filtered altitude and vertical speed.
+// A transition needs its condition to hold for some time, so noise or a
+// single bad measurement don't change the phase.
+
+const (
+	// climbing faster than this (m/s) or this altitude (m) over the
+	// ground is a launch
+	launchVSpeed  = 1.5
+	launchAltGain = 50.0
+	// over this altitude (m) from the ground the launch is an ascent
+	ascentAltGain = 300.0
+	// vertical speeds (m/s) under this are floating or landed
+	floatVSpeed  = 1.0
+	landedVSpeed = 0.5
+	// falling faster than this (m/s) is a burst, slower is a descent
+	burstVSpeed   = -8.0
+	descentVSpeed = -2.0
+
+	// time conditions must hold for a transition
+	launchHold  = time.Second * 10
+	ascentHold  = time.Second * 10
+	floatHold   = time.Minute * 3
+	burstHold   = time.Second * 10
+	descentHold = time.Second * 30
+	landedHold  = time.Minute
+)
+
+// Phase of the flight
+type Phase int
+
+const (
+	PhasePrelaunch Phase = iota // on the pad
+	PhaseLaunch                 // just released, climbing near the ground
+	PhaseAscent
+	PhaseFloat // not climbing nor falling, zero pressure or leaking balloon
+	PhaseBurst // falling fast after the burst, parachute opening
+	PhaseDescent
+	PhaseLanded
+)
+
+var phaseNames = []string{"prelaunch", "launch", "ascent", "float", "burst", "descent", "landed"}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return "unknown"
+	}
+	return phaseNames[p]
+}
+
+// ParsePhase returns the phase of a name, like "ascent"
+func ParsePhase(name string) (Phase, error) {
+	for i, n := range phaseNames {
+		if n == name {
+			return Phase(i), nil
+		}
+	}
+	return PhasePrelaunch, fmt.Errorf("Unknown flight phase: %s", name)
+}
+
+// phaseDetector keeps the phase and the transition candidate
+type phaseDetector struct {
+	phase     Phase
+	since     time.Time // start of the phase
+	next      Phase     // candidate phase
+	nextSince time.Time // since when the candidate condition holds
+	ground    float64   // launch site altitude (m)
+}
+
+func newPhaseDetector() phaseDetector {
+	return phaseDetector{ground: math.NaN()}
+}
+
+// candidate returns the next phase for the altitude and vertical speed,
+// and the time its condition must hold, or the current phase
+func (d *phaseDetector) candidate(alt float64, vs float64) (Phase, time.Duration) {
+	switch d.phase {
+	case PhasePrelaunch:
+		switch {
+		case vs < descentVSpeed:
+			// restarted in flight
+			return PhaseDescent, descentHold
+		case vs > launchVSpeed || alt > d.ground+launchAltGain:
+			return PhaseLaunch, launchHold
+		}
+	case PhaseLaunch:
+		switch {
+		case alt > d.ground+ascentAltGain:
+			return PhaseAscent, ascentHold
+		case math.Abs(vs) < landedVSpeed && alt < d.ground+launchAltGain:
+			// false start
+			return PhasePrelaunch, landedHold
+		}
+	case PhaseAscent, PhaseFloat:
+		switch {
+		case vs < burstVSpeed:
+			return PhaseBurst, burstHold
+		case vs < descentVSpeed:
+			return PhaseDescent, descentHold
+		case d.phase == PhaseAscent && vs < floatVSpeed:
+			return PhaseFloat, floatHold
+		case d.phase == PhaseFloat && vs > floatVSpeed:
+			return PhaseAscent, ascentHold
+		}
+	case PhaseBurst:
+		if vs > burstVSpeed {
+			return PhaseDescent, descentHold
+		}
+	case PhaseDescent:
+		if math.Abs(vs) < landedVSpeed {
+			return PhaseLanded, landedHold
+		}
+	}
+	return d.phase, 0
+}
+
+// update changes the phase with the filtered altitude and vertical speed
+// at time now, returns true if the phase changed
+func (d *phaseDetector) update(now time.Time, alt float64, vs float64) bool {
+	if d.since.IsZero() {
+		d.since = now
+	}
+	if d.phase == PhasePrelaunch && (math.IsNaN(d.ground) || math.Abs(vs) < landedVSpeed) {
+		// follow the pad altitude until the launch
+		d.ground = alt
+	}
+
+	next, hold := d.candidate(alt, vs)
+	if next == d.phase {
+		d.next = d.phase
+		return false
+	}
+	if next != d.next {
+		d.next = next
+		d.nextSince = now
+	}
+	if now.Sub(d.nextSince) < hold {
+		return false
+	}
+	d.phase = next
+	d.since = now
+	return true
+}
@@ -0,0 +1,84 @@
+package mission
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhases(t *testing.T) {
+	// pad, ascent to a float, burst, descent and landing
+	profile := []struct {
+		seconds int
+		vs      float64
+		want    Phase
+	}{
+		{300, 0, PhasePrelaunch},
+		{120, 5, PhaseAscent},
+		{5400, 5, PhaseAscent},
+		{600, 0.2, PhaseFloat},
+		{60, -30, PhaseBurst},
+		{1200, -6, PhaseDescent},
+		{300, 0, PhaseLanded},
+	}
+	d := newPhaseDetector()
+	now := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	alt := 545.0
+	var changes []Phase
+	for _, step := range profile {
+		for range step.seconds {
+			alt += step.vs
+			now = now.Add(time.Second)
+			if d.update(now, alt, step.vs) {
+				changes = append(changes, d.phase)
+			}
+		}
+		if d.phase != step.want {
+			t.Errorf("Phase at %.0fm, %.1fm/s: %v, want %v", alt, step.vs, d.phase, step.want)
+		}
+	}
+	want := []Phase{PhaseLaunch, PhaseAscent, PhaseFloat, PhaseBurst, PhaseDescent, PhaseLanded}
+	if len(changes) != len(want) {
+		t.Fatalf("Phase changes: %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Phase changes: %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestPhaseHysteresis(t *testing.T) {
+	d := newPhaseDetector()
+	now := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	d.update(now, 545, 0)
+	// gusts on the pad, never long enough for a launch
+	for i := range 120 {
+		now = now.Add(time.Second)
+		vs := 0.0
+		if i%10 < 5 {
+			vs = 2
+		}
+		if d.update(now, 545, vs) {
+			t.Fatalf("Phase changed to %v with gusts", d.phase)
+		}
+	}
+	// restarted while descending
+	for range 60 {
+		now = now.Add(time.Second)
+		d.update(now, 8000, -10)
+	}
+	if d.phase != PhaseDescent {
+		t.Errorf("Phase after restart in descent: %v", d.phase)
+	}
+}
+
+func TestParsePhase(t *testing.T) {
+	for p := PhasePrelaunch; p <= PhaseLanded; p++ {
+		if got, err := ParsePhase(p.String()); err != nil || got != p {
+			t.Errorf("ParsePhase(%q): %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := ParsePhase("orbit"); err == nil {
+		t.Error("Unknown phase parsed")
+	}
+}
@@ -0,0 +1,96 @@
+package mission
+
+import (
+	"math"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/atmosphere"
+	"github.com/ladecadence/EkiGo/pkg/gps"
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+// Landing prediction. The balloon drifts with the wind, so the GPS
+// velocity during the ascent is the wind profile. On the descent the
+// parachute falls at a speed proportional to 1/sqrt(air density), known
+// from the observed descent rate, and drifts with the measured winds
+// until the launch site altitude.
+
+const (
+	// altitude bins of the wind profile (m)
+	windBinSize = 250.0
+	// integration altitude step (m)
+	predictStep = 50.0
+	// slower descents (m/s) are not predicted
+	minDescentRate = 1.0
+)
+
+type windBin struct {
+	east, north float64 // sums of the velocities (m/s)
+	n           int
+}
+
+// windProfile is the average wind by altitude
+type windProfile struct {
+	bins map[int]*windBin
+}
+
+func newWindProfile() windProfile {
+	return windProfile{bins: map[int]*windBin{}}
+}
+
+// add adds a GPS velocity at an altitude, with the heading in degrees
+// and the speed in knots
+func (w *windProfile) add(alt float64, hdg float64, spd float64) {
+	i := int(math.Floor(alt / windBinSize))
+	b, ok := w.bins[i]
+	if !ok {
+		b = &windBin{}
+		w.bins[i] = b
+	}
+	v := spd / gps.KnotsPerMs
+	b.east += v * math.Sin(hdg*math.Pi/180.0)
+	b.north += v * math.Cos(hdg*math.Pi/180.0)
+	b.n++
+}
+
+// at returns the wind (m/s) at an altitude, from the nearest measured
+// altitude bin, or no wind without measurements
+func (w *windProfile) at(alt float64) (float64, float64) {
+	if len(w.bins) == 0 {
+		return 0, 0
+	}
+	i := int(math.Floor(alt / windBinSize))
+	for d := 0; ; d++ {
+		for _, j := range []int{i - d, i + d} {
+			if b, ok := w.bins[j]; ok {
+				return b.east / float64(b.n), b.north / float64(b.n)
+			}
+		}
+	}
+}
+
+// predictLanding returns the landing position and time from the current
+// position and vertical speed (m/s), falling to the ground altitude (m).
+// Returns false if not descending
+func predictLanding(pos position.Position, vs float64, ground float64, wind *windProfile) (position.Position, time.Duration, bool) {
+	if vs > -minDescentRate || pos.Alt <= ground {
+		return pos, 0, false
+	}
+	// descent rate at sea level density
+	rate0 := -vs * math.Sqrt(atmosphere.Density(pos.Alt)/atmosphere.Density(0))
+
+	var east, north, t float64
+	for alt := pos.Alt; alt > ground; alt -= predictStep {
+		dz := math.Min(predictStep, alt-ground)
+		mid := alt - dz/2
+		rate := rate0 * math.Sqrt(atmosphere.Density(0)/atmosphere.Density(mid))
+		dt := dz / rate
+		e, n := wind.at(mid)
+		east += e * dt
+		north += n * dt
+		t += dt
+	}
+	landing := pos.Move(east, north)
+	landing.Alt = ground
+	return landing, time.Duration(t * float64(time.Second)), true
+}
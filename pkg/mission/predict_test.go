@@ -0,0 +1,61 @@
+package mission
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/atmosphere"
+	"github.com/ladecadence/EkiGo/pkg/gps"
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestWindProfile(t *testing.T) {
+	w := newWindProfile()
+	if e, n := w.at(1000); e != 0 || n != 0 {
+		t.Errorf("Wind without measurements: %f, %f", e, n)
+	}
+	// east at 1000m, north at 5000m
+	w.add(1010, 90, 20)
+	w.add(1020, 90, 10)
+	w.add(5000, 0, 10)
+	if e, n := w.at(1100); math.Abs(e-15/gps.KnotsPerMs) > 1e-6 || math.Abs(n) > 1e-6 {
+		t.Errorf("Wind at 1100m: %f, %f", e, n)
+	}
+	if e, n := w.at(3500); math.Abs(e) > 1e-6 || math.Abs(n-10/gps.KnotsPerMs) > 1e-6 {
+		t.Errorf("Wind at 3500m: %f, %f", e, n)
+	}
+	if e, _ := w.at(-200); math.Abs(e-15/gps.KnotsPerMs) > 1e-6 {
+		t.Errorf("Wind under the profile: %f", e)
+	}
+}
+
+func TestPredictLanding(t *testing.T) {
+	// 10 m/s east wind, 5 m/s descent at sea level
+	w := newWindProfile()
+	w.add(0, 90, 10*gps.KnotsPerMs)
+	pos := position.Position{Lat: 43.5, Lon: -5.6, Alt: 3000}
+	vs := -5 * math.Sqrt(atmosphere.Density(0)/atmosphere.Density(pos.Alt))
+
+	landing, eta, ok := predictLanding(pos, vs, 500, &w)
+	if !ok {
+		t.Fatal("No prediction while descending")
+	}
+	// faster descent in thinner air
+	if eta < time.Second*440 || eta > time.Second*500 {
+		t.Errorf("Bad time to landing: %v", eta)
+	}
+	if d := pos.Distance(landing); math.Abs(d-10*eta.Seconds()) > 10 {
+		t.Errorf("Bad drift: %fm in %v", d, eta)
+	}
+	if math.Abs(landing.Lat-pos.Lat) > 1e-6 || landing.Lon <= pos.Lon || landing.Alt != 500 {
+		t.Errorf("Bad landing position: %+v", landing)
+	}
+
+	if _, _, ok := predictLanding(pos, 2, 500, &w); ok {
+		t.Error("Prediction while ascending")
+	}
+	if _, _, ok := predictLanding(pos, -5, 3500, &w); ok {
+		t.Error("Prediction under the ground altitude")
+	}
+}
@@ -0,0 +1,85 @@
+package mission
+
+import (
+	"fmt"
+
+	"github.com/ladecadence/EkiGo/pkg/atmosphere"
+	"github.com/ladecadence/EkiGo/pkg/gps"
+	"github.com/ladecadence/EkiGo/pkg/logging"
+	"github.com/ladecadence/EkiGo/pkg/mcp3002"
+)
+
+// Simulated hardware, to replay a recorded flight on a desktop: the
+// barometer and the external temperature follow the standard atmosphere
+// at the GPS altitude, and the radio packets go to the log
+
+const (
+	simVbat = 4.1  // V
+	simTin  = 20.0 // C
+)
+
+type simLED struct{}
+
+func (simLED) Blink() error      { return nil }
+func (simLED) BlinkError() error { return nil }
+
+type simBatt struct{}
+
+func (simBatt) ReadRaw(mcp3002.MCP3002, uint8) (uint32, error) { return 0, nil }
+func (simBatt) Read(mcp3002.MCP3002, uint8) (float64, error)   { return simVbat, nil }
+
+type simBaro struct {
+	gps  gps.GPS
+	pres float64
+	temp float64
+}
+
+func (b *simBaro) ReadProm() error              { return nil }
+func (b *simBaro) ReadADC(uint8) (int64, error) { return 0, nil }
+func (b *simBaro) GetTemp() float64             { return b.temp }
+func (b *simBaro) GetPres() float64             { return b.pres }
+func (b *simBaro) Update() error {
+	alt := b.gps.Alt()
+	b.pres = atmosphere.Pressure(alt)
+	b.temp = atmosphere.Temperature(alt)
+	return nil
+}
+
+// simThermometer returns the temperature of a function
+type simThermometer func() float64
+
+func (t simThermometer) Read() (float64, error) { return t(), nil }
+
+type simRadio struct {
+	log logging.Logging
+}
+
+func (simRadio) SetModemConfig([]uint8)                                                      {}
+func (simRadio) SetModemConfigCustom(uint8, uint8, uint8, uint8, uint8, uint8, uint8, uint8) {}
+func (simRadio) SetPreambleLength(uint16)                                                    {}
+func (simRadio) SetFrequency(float64) error                                                  { return nil }
+func (simRadio) SetModeSleep()                                                               {}
+func (simRadio) SetTxPower(uint8)                                                            {}
+func (r simRadio) Send(data []uint8) error {
+	return r.log.Log(logging.LogInfo, fmt.Sprintf("Simulated radio packet: %q", data))
+}
+func (simRadio) WaitPacketSent() bool     { return true }
+func (simRadio) Available() (bool, error) { return false, nil }
+func (simRadio) ClearRxBuf()              {}
+
+type simPwrsel struct{}
+
+func (simPwrsel) Read() bool { return false }
+
+// simulateHardware replaces the sensors, the LoRa radio and the GPIOs
+func (m *mission) simulateHardware() {
+	m.simulated = true
+	m.led = simLED{}
+	m.batt = simBatt{}
+	m.baro = &simBaro{gps: m.gps}
+	m.temp_internal = simThermometer(func() float64 { return simTin })
+	m.temp_external = simThermometer(func() float64 { return atmosphere.Temperature(m.gps.Alt()) })
+	m.lora = simRadio{log: m.log}
+	m.pwrSel = simPwrsel{}
+	m.log.Log(logging.LogWarn, "Simulating the sensors and the radio")
+}
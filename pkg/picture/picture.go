@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/fogleman/gg"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
 )
 
 // Constants
@@ -96,8 +98,9 @@ func (p *Picture) CaptureSmall(name string, res string, rotate bool) error {
 	return nil
 }
 
-func (p *Picture) AddInfo(file string, id string, subid string, msg string, data string) error {
+func (p *Picture) AddInfo(file string, id string, subid string, msg string, pos position.Position) error {
 	datetime := time.Now().Format(time.RFC3339)
+	data := pos.String()
 
 	// try to open image
 	image, err := gg.LoadImage(file)
@@ -3,12 +3,16 @@ package picture
 import (
 	"fmt"
 	"testing"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
 )
 
+var testPos = position.Position{Lat: 43.549067, Lon: -5.663050, Alt: 545.4}
+
 func TestPictureData(t *testing.T) {
 	pic := New(0, "test", "./")
 
-	err := pic.AddInfo("test.jpg", "ID", "USBID", "Test image, message", "Data: 1234567890")
+	err := pic.AddInfo("test.jpg", "ID", "USBID", "Test image, message", testPos)
 	if err != nil {
 		t.Errorf("Problem adding info to image: %v", err)
 	}
@@ -32,7 +36,7 @@ func TestPictureTake(t *testing.T) {
 	} else {
 		fmt.Printf("Picture shot: %s\n", pic.Path+"ssdv.jpg")
 	}
-	err = pic.AddInfo(pic.Path+"ssdv.jpg", "ID", "USBID", "Test image, message", "Data: 1234567890")
+	err = pic.AddInfo(pic.Path+"ssdv.jpg", "ID", "USBID", "Test image, message", testPos)
 	if err != nil {
 		t.Errorf("Problem adding info to image: %v", err)
 	}
@@ -0,0 +1,116 @@
+package position
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Position is a GPS position in signed decimal degrees
+type Position struct {
+	Lat  float64   // decimal degrees, positive north
+	Lon  float64   // decimal degrees, positive east
+	Alt  float64   // m over mean sea level
+	Time time.Time // UTC time of the fix, zero if unknown
+}
+
+// FromNMEA creates a position from NMEA ddmm.mmmm coordinates and hemispheres
+func FromNMEA(lat float64, ns string, lon float64, ew string, alt float64, t time.Time) Position {
+	p := Position{
+		Lat:  NmeaToDec(lat),
+		Lon:  NmeaToDec(lon),
+		Alt:  alt,
+		Time: t,
+	}
+	if ns == "S" {
+		p.Lat = -p.Lat
+	}
+	if ew == "W" {
+		p.Lon = -p.Lon
+	}
+	return p
+}
+
+// NmeaToDec converts NMEA ddmm.mmmm to decimal degrees
+func NmeaToDec(latlon float64) float64 {
+	degrees := math.Trunc(latlon / 100.0)
+	fraction := (latlon - (degrees * 100.0)) / 60.0
+
+	return degrees + fraction
+}
+
+// decToNmea converts decimal degrees to NMEA ddmm.mmmm
+func decToNmea(dec float64) float64 {
+	degrees := math.Trunc(dec)
+	return degrees*100.0 + (dec-degrees)*60.0
+}
+
+// NS returns the latitude hemisphere
+func (p Position) NS() string {
+	if p.Lat < 0 {
+		return "S"
+	}
+	return "N"
+}
+
+// EW returns the longitude hemisphere
+func (p Position) EW() string {
+	if p.Lon < 0 {
+		return "W"
+	}
+	return "E"
+}
+
+// NMEA returns the coordinates in NMEA ddmm.mmmm format and hemispheres
+func (p Position) NMEA() (float64, string, float64, string) {
+	return decToNmea(math.Abs(p.Lat)), p.NS(), decToNmea(math.Abs(p.Lon)), p.EW()
+}
+
+// APRS formats the coordinates as APRS uncompressed position,
+// like 4332.94N/00539.78W, sep is the symbol table character
+func (p Position) APRS(sep string) string {
+	lat, ns, lon, ew := p.NMEA()
+	return fmt.Sprintf("%07.2f%s%s%08.2f%s", lat, ns, sep, lon, ew)
+}
+
+// Decimal formats the coordinates as decimal degrees with hemispheres,
+// like 43.549067N,005.663050W
+func (p Position) Decimal() string {
+	return fmt.Sprintf("%09.6f%s,%010.6f%s", math.Abs(p.Lat), p.NS(), math.Abs(p.Lon), p.EW())
+}
+
+// CSV formats the coordinates as CSV fields, latitude, hemisphere,
+// longitude and hemisphere, like 43.549067,N,5.663050,W
+func (p Position) CSV() string {
+	return fmt.Sprintf("%f,%s,%f,%s", math.Abs(p.Lat), p.NS(), math.Abs(p.Lon), p.EW())
+}
+
+// String formats the position for logs and pictures,
+// like 43.549067N, 5.663050W, 545.4m
+func (p Position) String() string {
+	return fmt.Sprintf("%f%s, %f%s, %.1fm", math.Abs(p.Lat), p.NS(), math.Abs(p.Lon), p.EW(), p.Alt)
+}
+
+// earth mean radius (m)
+const earthRadius = 6371000.0
+
+// Distance returns the great circle distance to q in meters
+func (p Position) Distance(q Position) float64 {
+	lat1 := p.Lat * math.Pi / 180.0
+	lat2 := q.Lat * math.Pi / 180.0
+	dLat := lat2 - lat1
+	dLon := (q.Lon - p.Lon) * math.Pi / 180.0
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Move returns the position moved east and north meters, for short
+// distances
+func (p Position) Move(east float64, north float64) Position {
+	q := p
+	q.Lat += north / earthRadius * 180.0 / math.Pi
+	q.Lon += east / (earthRadius * math.Cos(p.Lat*math.Pi/180.0)) * 180.0 / math.Pi
+	return q
+}
@@ -0,0 +1,58 @@
+package position
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPosition(t *testing.T) {
+	p := FromNMEA(4332.944, "N", 539.783, "W", 545.4, time.Time{})
+	if math.Abs(p.Lat-43.549067) > 1e-6 || math.Abs(p.Lon+5.663050) > 1e-6 {
+		t.Errorf("Bad decimal position: %v %v", p.Lat, p.Lon)
+	}
+
+	if s := p.APRS("/"); s != "4332.94N/00539.78W" {
+		t.Errorf("Bad APRS coordinates: %s", s)
+	}
+	if s := p.Decimal(); s != "43.549067N,005.663050W" {
+		t.Errorf("Bad decimal coordinates: %s", s)
+	}
+	if s := p.CSV(); s != "43.549067,N,5.663050,W" {
+		t.Errorf("Bad CSV coordinates: %s", s)
+	}
+	if s := p.String(); s != "43.549067N, 5.663050W, 545.4m" {
+		t.Errorf("Bad position string: %s", s)
+	}
+
+	p = FromNMEA(3352.128, "S", 15112.558, "E", 0.0, time.Time{})
+	if p.NS() != "S" || p.EW() != "E" || p.Lat > 0 || p.Lon < 0 {
+		t.Errorf("Bad hemispheres: %v", p)
+	}
+	if s := p.APRS("/"); s != "3352.13S/15112.56E" {
+		t.Errorf("Bad APRS coordinates: %s", s)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// Gijón - Oviedo, ~24km
+	gijon := Position{Lat: 43.5322, Lon: -5.6611}
+	oviedo := Position{Lat: 43.3614, Lon: -5.8593}
+	if d := gijon.Distance(oviedo); math.Abs(d-24500) > 500 {
+		t.Errorf("Bad distance: %f", d)
+	}
+	if d := gijon.Distance(gijon); d != 0 {
+		t.Errorf("Expected 0 distance, got %f", d)
+	}
+}
+
+func TestMove(t *testing.T) {
+	gijon := Position{Lat: 43.5322, Lon: -5.6611, Alt: 100}
+	q := gijon.Move(3000, -4000)
+	if d := gijon.Distance(q); math.Abs(d-5000) > 5 {
+		t.Errorf("Bad moved distance: %f", d)
+	}
+	if q.Lat >= gijon.Lat || q.Lon <= gijon.Lon || q.Alt != gijon.Alt {
+		t.Errorf("Bad moved position: %+v", q)
+	}
+}
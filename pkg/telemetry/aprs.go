@@ -0,0 +1,221 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// APRS (APRS 1.01 protocol) position reports and telemetry
+
+const (
+	// experimental tocall
+	AprsDest = "APZEKI"
+	// balloon symbol in the primary table
+	aprsSymTable = '/'
+	aprsSymbol   = 'O'
+	// compression type: current GPS fix, RMC source, tracker origin
+	aprsCompType   = 0x20 | 0x18 | 0x06
+	aprsCompOldFix = 0x18 | 0x06
+
+	feetPerMeter = 3.28084
+
+	// AX.25 UI frame
+	ax25Control = 0x03
+	ax25PID     = 0xF0
+	ax25MaxPath = 8
+
+	// LoRa-APRS header before the TNC2 text
+	loraAprsHeader = "<\xff\x01"
+)
+
+// APRS telemetry analog channels, sent as 0-255 values,
+// value = a*x^2 + b*x + c
+type aprsChannel struct {
+	name    string
+	unit    string
+	a, b, c float64
+	value   func(t *telemetry) float64
+}
+
+var aprsChannels = []aprsChannel{
+	{"Vbat", "V", 0, 0.02, 0, func(t *telemetry) float64 { return t.vbat }},
+	{"Pres", "mbar", 0, 4.2, 0, func(t *telemetry) float64 { return t.baro }},
+	{"Tin", "C", 0, 0.5, -80, func(t *telemetry) float64 { return t.tin }},
+	{"Tout", "C", 0, 0.5, -100, func(t *telemetry) float64 { return t.tout }},
+	{"Sats", "sats", 0, 1, 0, func(t *telemetry) float64 { return float64(t.sats) }},
+}
+
+// APRS telemetry digital channels
+var aprsBits = []struct {
+	name  string
+	unit  string
+	value func(t *telemetry) bool
+}{
+	{"HiPwr", "on", func(t *telemetry) bool { return t.hpwr }},
+	{"GPS", "ok", func(t *telemetry) bool { return t.gpsHealth == "OK" }},
+	{"Valid", "ok", func(t *telemetry) bool { return t.status() == 0 }},
+}
+
+var ErrAX25Address = errors.New("Invalid AX.25 address")
+
+// base91 encodes v in n base-91 characters
+func base91(v int, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v%91) + 33
+		v /= 91
+	}
+	return string(b)
+}
+
+// AprsPosition returns an APRS compressed position report information
+// field with course, speed, altitude, the sequence number, boot counter,
+// uptime and the mission message as comment. Positions not from the
+// current fix are marked as old. Without a position (invalid) it's a
+// status report with the sequence number, boot counter and uptime
+func (t *telemetry) AprsPosition() string {
+	if t.fields.valid("pos") == Invalid {
+		return fmt.Sprintf(">No GPS fix S%d B%d U%d", t.counter, t.boot, int(t.uptime.Seconds()))
+	}
+	lat := math.Max(-90, math.Min(90, t.pos.Lat))
+	lon := math.Max(-180, math.Min(180, t.pos.Lon))
+	y := int(380926 * (90 - lat))
+	x := int(190463 * (180 + lon))
+
+	// course in 4 degrees steps, speed in knots, 1.08^s - 1
+	c := 0
+	if !math.IsNaN(t.hdg) {
+		c = int(math.Round(t.hdg/4)) % 90
+	}
+	s := 0
+	if t.spd > 0 {
+		s = min(int(math.Round(math.Log(t.spd+1)/math.Log(1.08))), 89)
+	}
+
+	alt := 0
+	if !math.IsNaN(t.pos.Alt) {
+		alt = max(min(int(math.Round(t.pos.Alt*feetPerMeter)), 999999), -99999)
+	}
+	comp := aprsCompType
+	if t.fields.valid("pos") != Valid {
+		comp = aprsCompOldFix
+	}
+	return fmt.Sprintf("!%c%s%s%c%c%c%c/A=%06d S%d B%d U%d %s",
+		aprsSymTable, base91(y, 4), base91(x, 4), aprsSymbol,
+		byte(c+33), byte(s+33), byte(comp+33),
+		alt, t.counter, t.boot, int(t.uptime.Seconds()), strings.ReplaceAll(t.msg, "\n", " - "))
+}
+
+// AprsTelemetry returns an APRS telemetry (T#) information field
+func (t *telemetry) AprsTelemetry() string {
+	values := make([]string, 0, len(aprsChannels))
+	for _, ch := range aprsChannels {
+		values = append(values, fmt.Sprintf("%03d", ch.raw(ch.value(t))))
+	}
+	bits := make([]byte, 8)
+	for i := range bits {
+		bits[i] = '0'
+		if i < len(aprsBits) && aprsBits[i].value(t) {
+			bits[i] = '1'
+		}
+	}
+	return fmt.Sprintf("T#%03d,%s,%s", t.counter%1000, strings.Join(values, ","), bits)
+}
+
+// raw converts a value to the 0-255 channel value, only for linear
+// channels, invalid values are 0
+func (ch aprsChannel) raw(v float64) int {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return int(math.Max(0, math.Min(255, math.Round((v-ch.c)/ch.b))))
+}
+
+// AprsDefinitions returns the APRS messages with the telemetry channel
+// names (PARM), units (UNIT) and equations (EQNS), sent to ourselves
+func (t *telemetry) AprsDefinitions() []string {
+	addr := fmt.Sprintf(":%-9s:", t.id)
+	var parm, unit, eqns []string
+	for _, ch := range aprsChannels {
+		parm = append(parm, ch.name)
+		unit = append(unit, ch.unit)
+		eqns = append(eqns, strconv.FormatFloat(ch.a, 'f', -1, 64),
+			strconv.FormatFloat(ch.b, 'f', -1, 64),
+			strconv.FormatFloat(ch.c, 'f', -1, 64))
+	}
+	for _, b := range aprsBits {
+		parm = append(parm, b.name)
+		unit = append(unit, b.unit)
+	}
+	return []string{
+		addr + "PARM." + strings.Join(parm, ","),
+		addr + "UNIT." + strings.Join(unit, ","),
+		addr + "EQNS." + strings.Join(eqns, ","),
+	}
+}
+
+// LoraAprs returns the packet for LoRa-APRS iGates: a header and the TNC2
+// monitor format, SRC>DEST,PATH:info
+func LoraAprs(src string, path []string, info string) []byte {
+	addrs := append([]string{AprsDest}, path...)
+	return []byte(loraAprsHeader + src + ">" + strings.Join(addrs, ",") + ":" + info)
+}
+
+// ax25Address encodes a CALL-SSID address field, the destination
+// has the command bit set
+func ax25Address(addr string, dest bool, last bool) ([]byte, error) {
+	call, ssid := addr, 0
+	if i := strings.IndexByte(addr, '-'); i >= 0 {
+		var err error
+		call = addr[:i]
+		ssid, err = strconv.Atoi(addr[i+1:])
+		if err != nil || ssid < 0 || ssid > 15 {
+			return nil, fmt.Errorf("%w: %s", ErrAX25Address, addr)
+		}
+	}
+	if len(call) == 0 || len(call) > 6 {
+		return nil, fmt.Errorf("%w: %s", ErrAX25Address, addr)
+	}
+	field := make([]byte, 7)
+	for i := range 6 {
+		c := byte(' ')
+		if i < len(call) {
+			c = call[i]
+			if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+				return nil, fmt.Errorf("%w: %s", ErrAX25Address, addr)
+			}
+		}
+		field[i] = c << 1
+	}
+	// reserved bits set
+	field[6] = 0x60 | byte(ssid)<<1
+	if dest {
+		field[6] |= 0x80
+	}
+	if last {
+		field[6] |= 0x01
+	}
+	return field, nil
+}
+
+// EncodeAX25 creates an AX.25 UI frame with the information field,
+// without flags and FCS, like KISS TNCs expect them
+func EncodeAX25(src string, path []string, info string) ([]byte, error) {
+	if len(path) > ax25MaxPath {
+		return nil, fmt.Errorf("%w: too many digipeaters", ErrAX25Address)
+	}
+	addrs := append([]string{AprsDest, src}, path...)
+	frame := make([]byte, 0, len(addrs)*7+2+len(info))
+	for i, addr := range addrs {
+		field, err := ax25Address(strings.ToUpper(addr), i == 0, i == len(addrs)-1)
+		if err != nil {
+			return nil, err
+		}
+		frame = append(frame, field...)
+	}
+	frame = append(frame, ax25Control, ax25PID)
+	return append(frame, info...), nil
+}
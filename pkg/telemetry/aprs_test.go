@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestAprsPosition(t *testing.T) {
+	// example from the APRS 1.01 spec: 49 30'N, 72 45'W, 88 degrees, 36.2 knots,
+	// current GPS fix from RMC sentences (compression type "_")
+	telem := New("EA1IDZ-11", "Test", "/")
+	pos := position.Position{Lat: 49.5, Lon: -72.75, Alt: 1000}
+	telem.SetBoot(2)
+	telem.Update(pos, 88, 36.2, 8, "OK", 4.1, 1013, 20, 15, false)
+
+	aprs := telem.AprsPosition()
+	if !strings.HasPrefix(aprs, "!/5L!!<*e7O7P_") {
+		t.Errorf("Bad compressed position: %s", aprs)
+	}
+	if !strings.Contains(aprs, "/A=003281 S1 B2 U") || !strings.HasSuffix(aprs, " Test") {
+		t.Errorf("Bad altitude and sequence comment: %s", aprs)
+	}
+}
+
+func TestAprsTelemetry(t *testing.T) {
+	telem := New("EA1IDZ-11", "Test", "/")
+	pos := position.FromNMEA(4332.944, "N", 539.783, "W", 545.4, time.Time{})
+	telem.Update(pos, 0, 0, 8, "OK", 4.1, 1013, 20, -12.5, true)
+
+	tlm := telem.AprsTelemetry()
+	if tlm != "T#001,205,241,200,175,008,11100000" {
+		t.Errorf("Bad APRS telemetry: %s", tlm)
+	}
+	defs := telem.AprsDefinitions()
+	if len(defs) != 3 ||
+		defs[0] != ":EA1IDZ-11:PARM.Vbat,Pres,Tin,Tout,Sats,HiPwr,GPS,Valid" ||
+		defs[1] != ":EA1IDZ-11:UNIT.V,mbar,C,C,sats,on,ok,ok" ||
+		defs[2] != ":EA1IDZ-11:EQNS.0,0.02,0,0,4.2,0,0,0.5,-80,0,0.5,-100,0,1,0" {
+		t.Errorf("Bad APRS telemetry definitions: %q", defs)
+	}
+}
+
+func TestAX25(t *testing.T) {
+	frame, err := EncodeAX25("EA1IDZ-11", []string{"WIDE2-1"}, "!test")
+	if err != nil {
+		t.Fatalf("Error encoding AX.25 frame: %v", err)
+	}
+	want := []byte{
+		'A' << 1, 'P' << 1, 'Z' << 1, 'E' << 1, 'K' << 1, 'I' << 1, 0xE0,
+		'E' << 1, 'A' << 1, '1' << 1, 'I' << 1, 'D' << 1, 'Z' << 1, 0x60 | 11<<1,
+		'W' << 1, 'I' << 1, 'D' << 1, 'E' << 1, '2' << 1, ' ' << 1, 0x60 | 1<<1 | 1,
+		0x03, 0xF0, '!', 't', 'e', 's', 't',
+	}
+	if !bytes.Equal(frame, want) {
+		t.Errorf("Bad AX.25 frame:\n%x\n%x", frame, want)
+	}
+	for _, bad := range []string{"TOOLONGCALL", "EA1IDZ-16", "EA1/DZ", ""} {
+		if _, err := EncodeAX25(bad, nil, "!"); !errors.Is(err, ErrAX25Address) {
+			t.Errorf("Bad address %q accepted", bad)
+		}
+	}
+	if p := string(LoraAprs("EA1IDZ-11", []string{"WIDE1-1"}, "!test")); p != "<\xff\x01EA1IDZ-11>APZEKI,WIDE1-1:!test" {
+		t.Errorf("Bad LoRa-APRS packet: %q", p)
+	}
+}
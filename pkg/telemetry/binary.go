@@ -0,0 +1,211 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+// Binary packet, little endian:
+//
+//	offset size field
+//	0      1    version
+//	1      10   id, zero padded
+//	11     2    counter, sequence number
+//	13     4    time, unix seconds
+//	17     4    lat, 1e-7 degrees
+//	21     4    lon, 1e-7 degrees
+//	25     4    alt, dm
+//	29     2    hdg, 0.1 degrees
+//	31     2    spd, 0.1 knots
+//	33     1    sats
+//	34     2    vbat, mV
+//	36     2    baro, 0.1 mbar
+//	38     2    tin, 0.1 C
+//	40     2    tout, 0.1 C
+//	42     2    arate, 0.1 m/s
+//	44     1    flags, bit 0 high power, bits 1-2 GPS health
+//	45     4    uptime, seconds (version 2)
+//	49     2    boot counter (version 2)
+//	51     4    status bitfield (version 3)
+//	55     2    CRC16-CCITT of the previous bytes
+//
+// Version 1 packets, without uptime and boot counter, are 47 bytes long,
+// version 2 packets, without status, 53 bytes. Invalid values are sent
+// as 0 and flagged in the status.
+const (
+	BinaryVersion = 3
+	BinaryLen     = 57
+	binaryV1Len   = 47
+	binaryV2Len   = 53
+	binaryIDLen   = 10
+
+	flagHighPwr     = 0x01
+	flagHealthShift = 1
+	flagHealthMask  = 0x03
+)
+
+// GPS health names, by their code in the flags
+var binaryHealth = []string{"OK", "NODATA", "BADDATA", "DISCONNECTED"}
+
+var (
+	ErrBinaryLength  = errors.New("Bad binary packet length")
+	ErrBinaryVersion = errors.New("Unknown binary packet version")
+	ErrBinaryCRC     = errors.New("Binary packet CRC mismatch")
+)
+
+// Packet is a decoded binary telemetry packet
+type Packet struct {
+	ID        string
+	Counter   int
+	Time      time.Time
+	Pos       position.Position
+	Hdg       float64
+	Spd       float64
+	Sats      int
+	Vbat      float64
+	Baro      float64
+	Tin       float64
+	Tout      float64
+	ARate     float64
+	HighPwr   bool
+	GpsHealth string
+	Uptime    time.Duration // 0 in version 1 packets
+	Boot      int           // 0 in version 1 packets
+	Status    Status        // 0 before version 3, invalid values are NaN
+}
+
+// invalidate sets the invalid values of the status to NaN
+func (p *Packet) invalidate() {
+	values := map[string][]*float64{
+		"pos":   {&p.Pos.Lat, &p.Pos.Lon},
+		"alt":   {&p.Pos.Alt},
+		"hdg":   {&p.Hdg},
+		"spd":   {&p.Spd},
+		"vbat":  {&p.Vbat},
+		"baro":  {&p.Baro},
+		"tin":   {&p.Tin},
+		"tout":  {&p.Tout},
+		"arate": {&p.ARate},
+	}
+	for name, vs := range values {
+		if p.Status.Validity(name) == Invalid {
+			for _, v := range vs {
+				*v = math.NaN()
+			}
+		}
+	}
+}
+
+// scale rounds v*factor to an integer, clamped to [lo, hi], 0 if NaN
+func scale(v float64, factor float64, lo float64, hi float64) int64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return int64(math.Max(lo, math.Min(hi, math.Round(v*factor))))
+}
+
+// Binary returns the telemetry as a binary packet
+func (t *telemetry) Binary() []byte {
+	// invalid values as NaN, sent as 0
+	valid := func(name string, v float64) float64 {
+		if t.fields.valid(name) == Invalid {
+			return math.NaN()
+		}
+		return v
+	}
+	le := binary.LittleEndian
+	p := make([]byte, 0, BinaryLen)
+	p = append(p, BinaryVersion)
+	id := make([]byte, binaryIDLen)
+	copy(id, t.id)
+	p = append(p, id...)
+	p = le.AppendUint16(p, uint16(t.counter))
+	p = le.AppendUint32(p, uint32(t.dateTime.Unix()))
+	p = le.AppendUint32(p, uint32(int32(scale(valid("pos", t.pos.Lat), 1e7, -90e7, 90e7))))
+	p = le.AppendUint32(p, uint32(int32(scale(valid("pos", t.pos.Lon), 1e7, -180e7, 180e7))))
+	p = le.AppendUint32(p, uint32(int32(scale(valid("alt", t.pos.Alt), 10, math.MinInt32, math.MaxInt32))))
+	p = le.AppendUint16(p, uint16(scale(valid("hdg", t.hdg), 10, 0, 3600)))
+	p = le.AppendUint16(p, uint16(scale(valid("spd", t.spd), 10, 0, math.MaxUint16)))
+	p = append(p, byte(min(max(t.sats, 0), math.MaxUint8)))
+	p = le.AppendUint16(p, uint16(scale(valid("vbat", t.vbat), 1000, 0, math.MaxUint16)))
+	p = le.AppendUint16(p, uint16(scale(valid("baro", t.baro), 10, 0, math.MaxUint16)))
+	p = le.AppendUint16(p, uint16(int16(scale(valid("tin", t.tin), 10, math.MinInt16, math.MaxInt16))))
+	p = le.AppendUint16(p, uint16(int16(scale(valid("tout", t.tout), 10, math.MinInt16, math.MaxInt16))))
+	p = le.AppendUint16(p, uint16(int16(scale(valid("arate", t.fields.values["arate"].(float64)), 10, math.MinInt16, math.MaxInt16))))
+	flags := byte(0)
+	if t.hpwr {
+		flags |= flagHighPwr
+	}
+	for code, name := range binaryHealth {
+		if name == t.gpsHealth {
+			flags |= byte(code) << flagHealthShift
+		}
+	}
+	p = append(p, flags)
+	p = le.AppendUint32(p, uint32(t.uptime.Seconds()))
+	p = le.AppendUint16(p, uint16(min(max(t.boot, 0), math.MaxUint16)))
+	p = le.AppendUint32(p, uint32(t.status()))
+	return le.AppendUint16(p, CRC16(p))
+}
+
+// DecodeBinary checks and decodes a binary telemetry packet, version 1 to 3
+func DecodeBinary(data []byte) (Packet, error) {
+	if len(data) == 0 {
+		return Packet{}, ErrBinaryLength
+	}
+	var length int
+	switch data[0] {
+	case 1:
+		length = binaryV1Len
+	case 2:
+		length = binaryV2Len
+	case BinaryVersion:
+		length = BinaryLen
+	default:
+		return Packet{}, ErrBinaryVersion
+	}
+	if len(data) != length {
+		return Packet{}, ErrBinaryLength
+	}
+	le := binary.LittleEndian
+	if CRC16(data[:length-2]) != le.Uint16(data[length-2:]) {
+		return Packet{}, ErrBinaryCRC
+	}
+	flags := data[44]
+	tm := time.Unix(int64(le.Uint32(data[13:17])), 0).UTC()
+	p := Packet{
+		ID:      string(bytes.TrimRight(data[1:11], "\x00")),
+		Counter: int(le.Uint16(data[11:13])),
+		Time:    tm,
+		Pos: position.Position{
+			Lat:  float64(int32(le.Uint32(data[17:21]))) / 1e7,
+			Lon:  float64(int32(le.Uint32(data[21:25]))) / 1e7,
+			Alt:  float64(int32(le.Uint32(data[25:29]))) / 10,
+			Time: tm,
+		},
+		Hdg:       float64(le.Uint16(data[29:31])) / 10,
+		Spd:       float64(le.Uint16(data[31:33])) / 10,
+		Sats:      int(data[33]),
+		Vbat:      float64(le.Uint16(data[34:36])) / 1000,
+		Baro:      float64(le.Uint16(data[36:38])) / 10,
+		Tin:       float64(int16(le.Uint16(data[38:40]))) / 10,
+		Tout:      float64(int16(le.Uint16(data[40:42]))) / 10,
+		ARate:     float64(int16(le.Uint16(data[42:44]))) / 10,
+		HighPwr:   flags&flagHighPwr != 0,
+		GpsHealth: binaryHealth[flags>>flagHealthShift&flagHealthMask],
+	}
+	if data[0] >= 2 {
+		p.Uptime = time.Duration(le.Uint32(data[45:49])) * time.Second
+		p.Boot = int(le.Uint16(data[49:51]))
+	}
+	if data[0] >= 3 {
+		p.Status = Status(le.Uint32(data[51:55]))
+		p.invalidate()
+	}
+	return p, nil
+}
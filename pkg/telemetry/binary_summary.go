@@ -0,0 +1,107 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Binary flight summary packet, little endian:
+//
+//	offset size field
+//	0      1    version, with bit 7 set (0x81)
+//	1      10   id, zero padded
+//	11     4    max alt, dm
+//	15     4    max alt time, unix seconds
+//	19     2    min tin, 0.1 C
+//	21     2    min tout, 0.1 C
+//	23     2    min vbat, mV
+//	25     2    max ascent rate, 0.1 m/s
+//	27     2    max descent rate, 0.1 m/s
+//	29     1    unknown values, bit i set if the value i is unknown
+//	30     2    CRC16-CCITT of the previous bytes
+//
+// The version byte never matches a telemetry packet version, so both can
+// be received in the same channel.
+const (
+	BinarySummaryVersion = 0x81
+	BinarySummaryLen     = 32
+)
+
+// SummaryPacket is a decoded binary flight summary packet
+type SummaryPacket struct {
+	ID string
+	Extremes
+}
+
+// binary summary values, in the unknown bits order
+func summaryFloats(e *Extremes) []*float64 {
+	return []*float64{&e.MaxAlt, &e.MinTin, &e.MinTout, &e.MinVbat, &e.MaxAscent, &e.MaxDescent}
+}
+
+// BinarySummary returns the flight summary as a binary packet
+func (t *telemetry) BinarySummary(e Extremes) []byte {
+	le := binary.LittleEndian
+	p := make([]byte, 0, BinarySummaryLen)
+	p = append(p, BinarySummaryVersion)
+	id := make([]byte, binaryIDLen)
+	copy(id, t.id)
+	p = append(p, id...)
+	p = le.AppendUint32(p, uint32(int32(scale(e.MaxAlt, 10, math.MinInt32, math.MaxInt32))))
+	maxAltTime := uint32(0)
+	if !e.MaxAltTime.IsZero() {
+		maxAltTime = uint32(e.MaxAltTime.Unix())
+	}
+	p = le.AppendUint32(p, maxAltTime)
+	p = le.AppendUint16(p, uint16(int16(scale(e.MinTin, 10, math.MinInt16, math.MaxInt16))))
+	p = le.AppendUint16(p, uint16(int16(scale(e.MinTout, 10, math.MinInt16, math.MaxInt16))))
+	p = le.AppendUint16(p, uint16(scale(e.MinVbat, 1000, 0, math.MaxUint16)))
+	p = le.AppendUint16(p, uint16(scale(e.MaxAscent, 10, 0, math.MaxUint16)))
+	p = le.AppendUint16(p, uint16(scale(e.MaxDescent, 10, 0, math.MaxUint16)))
+	unknown := byte(0)
+	for i, v := range summaryFloats(&e) {
+		if math.IsNaN(*v) {
+			unknown |= 1 << i
+		}
+	}
+	p = append(p, unknown)
+	return le.AppendUint16(p, CRC16(p))
+}
+
+// DecodeBinarySummary checks and decodes a binary flight summary packet
+func DecodeBinarySummary(data []byte) (SummaryPacket, error) {
+	if len(data) == 0 {
+		return SummaryPacket{}, ErrBinaryLength
+	}
+	if data[0] != BinarySummaryVersion {
+		return SummaryPacket{}, ErrBinaryVersion
+	}
+	if len(data) != BinarySummaryLen {
+		return SummaryPacket{}, ErrBinaryLength
+	}
+	le := binary.LittleEndian
+	if CRC16(data[:BinarySummaryLen-2]) != le.Uint16(data[BinarySummaryLen-2:]) {
+		return SummaryPacket{}, ErrBinaryCRC
+	}
+	p := SummaryPacket{
+		ID: string(bytes.TrimRight(data[1:11], "\x00")),
+		Extremes: Extremes{
+			MaxAlt:     float64(int32(le.Uint32(data[11:15]))) / 10,
+			MinTin:     float64(int16(le.Uint16(data[19:21]))) / 10,
+			MinTout:    float64(int16(le.Uint16(data[21:23]))) / 10,
+			MinVbat:    float64(le.Uint16(data[23:25])) / 1000,
+			MaxAscent:  float64(le.Uint16(data[25:27])) / 10,
+			MaxDescent: float64(le.Uint16(data[27:29])) / 10,
+		},
+	}
+	if tm := le.Uint32(data[15:19]); tm != 0 {
+		p.MaxAltTime = time.Unix(int64(tm), 0).UTC()
+	}
+	for i, v := range summaryFloats(&p.Extremes) {
+		if data[29]&(1<<i) != 0 {
+			*v = math.NaN()
+		}
+	}
+	return p, nil
+}
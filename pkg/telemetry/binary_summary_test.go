@@ -0,0 +1,43 @@
+package telemetry
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBinarySummary(t *testing.T) {
+	e := NewExtremes()
+	e.Update(time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC), 31234.5, math.NaN(), -58.2, 3.9, 5.8)
+	e.Update(time.Date(2026, 10, 18, 7, 1, 0, 0, time.UTC), 30000, math.NaN(), -50, math.NaN(), -35.2)
+	telem := New("EA1IDZ-11", "Test", "/")
+
+	data := telem.BinarySummary(e)
+	if len(data) != BinarySummaryLen {
+		t.Fatalf("Bad binary summary length: %d", len(data))
+	}
+	p, err := DecodeBinarySummary(data)
+	if err != nil {
+		t.Fatalf("Error decoding binary summary: %v", err)
+	}
+	if p.ID != "EA1IDZ-11" || p.MaxAlt != 31234.5 || !p.MaxAltTime.Equal(e.MaxAltTime) || !math.IsNaN(p.MinTin) ||
+		p.MinTout != -58.2 || p.MinVbat != 3.9 || p.MaxAscent != 5.8 || p.MaxDescent != 35.2 {
+		t.Errorf("Bad decoded binary summary: %+v", p)
+	}
+
+	// not a telemetry packet
+	if _, err := DecodeBinary(data); !errors.Is(err, ErrBinaryVersion) {
+		t.Errorf("Binary summary decoded as telemetry: %v", err)
+	}
+	if _, err := DecodeBinarySummary(telem.Binary()); !errors.Is(err, ErrBinaryVersion) {
+		t.Errorf("Telemetry decoded as binary summary: %v", err)
+	}
+	data[12] ^= 0x01
+	if _, err := DecodeBinarySummary(data); !errors.Is(err, ErrBinaryCRC) {
+		t.Errorf("Corrupted binary summary decoded: %v", err)
+	}
+	if _, err := DecodeBinarySummary(data[:20]); !errors.Is(err, ErrBinaryLength) {
+		t.Errorf("Short binary summary decoded: %v", err)
+	}
+}
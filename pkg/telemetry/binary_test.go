@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestBinary(t *testing.T) {
+	telem := New("EA1IDZ-11", "Test telemetry message", "/")
+	pos := position.FromNMEA(4332.944, "N", 539.783, "W", 12345.6, time.Time{})
+	telem.SetBoot(3)
+	telem.Update(pos, 271.3, 12.4, 9, "BADDATA", 4.123, 15.7, -52.3, -61.8, true)
+	telem.Set("arate", -5.3)
+
+	data := telem.Binary()
+	if len(data) != BinaryLen {
+		t.Fatalf("Bad binary packet length: %d", len(data))
+	}
+	p, err := DecodeBinary(data)
+	if err != nil {
+		t.Fatalf("Error decoding binary packet: %v", err)
+	}
+	near := func(a, b, e float64) bool { return math.Abs(a-b) <= e }
+	if p.ID != "EA1IDZ-11" || p.Counter != 1 || p.Sats != 9 || !p.HighPwr || p.GpsHealth != "BADDATA" {
+		t.Errorf("Bad decoded packet: %+v", p)
+	}
+	if !near(p.Pos.Lat, pos.Lat, 1e-7) || !near(p.Pos.Lon, pos.Lon, 1e-7) || p.Pos.Alt != 12345.6 {
+		t.Errorf("Bad decoded position: %+v", p.Pos)
+	}
+	if p.Hdg != 271.3 || p.Spd != 12.4 || p.Vbat != 4.123 || p.Baro != 15.7 ||
+		p.Tin != -52.3 || p.Tout != -61.8 || p.ARate != -5.3 {
+		t.Errorf("Bad decoded values: %+v", p)
+	}
+	if time.Since(p.Time) > time.Second*2 {
+		t.Errorf("Bad decoded time: %v", p.Time)
+	}
+	if p.Boot != 3 || p.Uptime < 0 || p.Uptime > time.Since(processStart) {
+		t.Errorf("Bad decoded boot counter and uptime: %d, %v", p.Boot, p.Uptime)
+	}
+
+	// version 1 packets
+	v1 := append([]byte{1}, data[1:45]...)
+	v1 = binary.LittleEndian.AppendUint16(v1, CRC16(v1))
+	p1, err := DecodeBinary(v1)
+	if err != nil || p1.Counter != 1 || p1.Sats != 9 || p1.Boot != 0 || p1.Uptime != 0 {
+		t.Errorf("Bad decoded version 1 packet: %+v, %v", p1, err)
+	}
+
+	// invalid and stale values
+	telem.Update(pos, 271.3, 12.4, 9, "OK", 4.123, 15.7, math.NaN(), -61.8, true)
+	telem.Set("arate", -5.3)
+	telem.SetValidity(Stale, "pos")
+	p3, err := DecodeBinary(telem.Binary())
+	if err != nil || !math.IsNaN(p3.Tin) || p3.Tout != -61.8 || p3.Status.Validity("tin") != Invalid ||
+		p3.Status.Validity("pos") != Stale || !near(p3.Pos.Lat, pos.Lat, 1e-7) {
+		t.Errorf("Bad decoded invalid values: %+v, %v", p3, err)
+	}
+
+	// invalid position and altitude, sent as 0
+	telem.SetValidity(Invalid, "pos", "alt")
+	data3 := telem.Binary()
+	p3, err = DecodeBinary(data3)
+	if err != nil || !math.IsNaN(p3.Pos.Lat) || !math.IsNaN(p3.Pos.Lon) || !math.IsNaN(p3.Pos.Alt) ||
+		p3.Status.Validity("pos") != Invalid || p3.Status.Validity("alt") != Invalid {
+		t.Errorf("Bad decoded invalid position: %+v, %v", p3, err)
+	}
+	for _, b := range data3[17:29] {
+		if b != 0 {
+			t.Errorf("Invalid position not sent as 0: % x", data3[17:29])
+			break
+		}
+	}
+
+	// errors
+	data[20] ^= 0x01
+	if _, err := DecodeBinary(data); !errors.Is(err, ErrBinaryCRC) {
+		t.Errorf("Corrupted packet decoded: %v", err)
+	}
+	if _, err := DecodeBinary(data[:20]); !errors.Is(err, ErrBinaryLength) {
+		t.Errorf("Short packet decoded: %v", err)
+	}
+	data[0] = 99
+	if _, err := DecodeBinary(data); !errors.Is(err, ErrBinaryVersion) {
+		t.Errorf("Unknown version decoded: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Self describing datalog: the CSV datalog starts with a schema comment
+// and the header, the JSON-lines datalog with a schema object with the
+// fields, units and types, followed by one object per line
+
+const (
+	// datalog format version, the fields are described in the datalog.
+	// Changed with the default columns: 2 added seq, uptime, boot and status
+	DatalogVersion = 2
+	datalogSchema  = "ekigo-datalog"
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FloatField:
+		return "float"
+	case IntField:
+		return "int"
+	case BoolField:
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+// CsvSchema returns the first line of the CSV datalog, before the header
+func (t *telemetry) CsvSchema() string {
+	return fmt.Sprintf("# %s v%d, id=%s, format=csv", datalogSchema, DatalogVersion, t.id)
+}
+
+type jsonField struct {
+	Name string `json:"name"`
+	Unit string `json:"unit,omitempty"`
+	Type string `json:"type"`
+}
+
+// JsonSchema returns the first line of the JSON-lines datalog, with the
+// fields of the CSV datalog. The position is sent as lat and lon
+func (t *telemetry) JsonSchema() string {
+	fields := []jsonField{
+		{Name: "ts", Type: "time"},
+		{Name: "lat", Unit: "deg", Type: FloatField.String()},
+		{Name: "lon", Unit: "deg", Type: FloatField.String()},
+	}
+	for _, name := range t.fields.csv {
+		if name == "pos" {
+			continue
+		}
+		f := t.fields.fields[name]
+		fields = append(fields, jsonField{Name: f.Name, Unit: f.Unit, Type: f.Type.String()})
+	}
+	schema, _ := json.Marshal(struct {
+		Schema  string      `json:"schema"`
+		Version int         `json:"version"`
+		ID      string      `json:"id"`
+		Format  string      `json:"format"`
+		Fields  []jsonField `json:"fields"`
+	}{datalogSchema, DatalogVersion, t.id, "jsonl", fields})
+	return string(schema)
+}
+
+// jsonValue returns the JSON value of a field, null if not set or not a number
+func jsonValue(v any) string {
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return "null"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+// JsonString returns the telemetry as a JSON object with the fields of
+// the CSV datalog, in order, with the full precision values and timestamp,
+// invalid values are null
+func (t *telemetry) JsonString() string {
+	var b strings.Builder
+	b.WriteString(`{"ts":`)
+	b.WriteString(jsonValue(t.dateTime.Format(time.RFC3339Nano)))
+	lat, lon := "null", "null"
+	if t.fields.valid("pos") != Invalid {
+		lat, lon = jsonValue(t.pos.Lat), jsonValue(t.pos.Lon)
+	}
+	b.WriteString(`,"lat":` + lat)
+	b.WriteString(`,"lon":` + lon)
+	for _, name := range t.fields.csv {
+		if name == "pos" {
+			continue
+		}
+		value := "null"
+		if t.fields.valid(name) != Invalid {
+			value = jsonValue(t.fields.values[name])
+		}
+		b.WriteString("," + jsonValue(name) + ":" + value)
+	}
+	b.WriteString("}")
+	return b.String()
+}
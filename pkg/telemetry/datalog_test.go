@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestJsonDatalog(t *testing.T) {
+	telem := New("EA1IDZ-11", "Test", "/")
+	err := telem.Register(Field{Name: "balt", Key: "BA", Unit: "m", Type: FloatField, Precision: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := position.Position{Lat: 43.5490671, Lon: -5.6630502, Alt: 12345.67}
+	telem.Update(pos, 271.3, 12.4, 9, "OK", 4.123, 150.3, -42.5, -55.1, true)
+	telem.Set("arate", -5.25)
+
+	var schema struct {
+		Schema  string
+		Version int
+		ID      string
+		Fields  []struct{ Name, Unit, Type string }
+	}
+	if err := json.Unmarshal([]byte(telem.JsonSchema()), &schema); err != nil {
+		t.Fatalf("Bad JSON schema %s: %v", telem.JsonSchema(), err)
+	}
+	if schema.Schema != "ekigo-datalog" || schema.Version != DatalogVersion || schema.ID != "EA1IDZ-11" {
+		t.Errorf("Bad JSON schema: %+v", schema)
+	}
+	names := []string{}
+	for _, f := range schema.Fields {
+		names = append(names, f.Name)
+		if f.Name == "alt" && (f.Unit != "m" || f.Type != "float") {
+			t.Errorf("Bad alt field schema: %+v", f)
+		}
+	}
+	if n := strings.Join(names, ","); n != "ts,lat,lon,date,time,alt,vbat,tin,tout,baro,hdg,spd,sats,arate,pwr,gps_health,seq,uptime,boot,status,balt" {
+		t.Errorf("Bad JSON schema fields: %s", n)
+	}
+
+	// full precision values, in order, unset fields are null
+	line := telem.JsonString()
+	if !strings.HasPrefix(line, `{"ts":"`) || !strings.HasSuffix(line, `,"balt":null}`) {
+		t.Errorf("Bad JSON line: %s", line)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		t.Fatalf("Bad JSON line %s: %v", line, err)
+	}
+	if record["lat"] != 43.5490671 || record["lon"] != -5.6630502 || record["alt"] != 12345.67 ||
+		record["vbat"] != 4.123 || record["sats"] != 9.0 || record["arate"] != -5.25 || record["pwr"] != "H" {
+		t.Errorf("Bad JSON values: %v", record)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, record["ts"].(string))
+	if err != nil || time.Since(ts) > time.Second {
+		t.Errorf("Bad JSON timestamp: %v, %v", record["ts"], err)
+	}
+
+	telem.Set("balt", math.NaN())
+	if line := telem.JsonString(); !strings.HasSuffix(line, `,"balt":null}`) {
+		t.Errorf("NaN not null: %s", line)
+	}
+	if s := telem.CsvSchema(); s != "# ekigo-datalog v2, id=EA1IDZ-11, format=csv" {
+		t.Errorf("Bad CSV schema: %s", s)
+	}
+}
@@ -0,0 +1,216 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FieldType is the type of the values of a field
+type FieldType int
+
+const (
+	FloatField  FieldType = iota // float64
+	IntField                     // int
+	BoolField                    // bool, formatted as 1 or 0
+	StringField                  // string
+)
+
+var ErrUnknownField = errors.New("Unknown telemetry field")
+
+// Field is a named telemetry value included in the radio string and
+// the CSV datalog
+type Field struct {
+	Name      string // unique, used in the config and the CSV header
+	Key       string // radio string key, like V in V=4.12, empty for no key
+	Unit      string
+	Type      FieldType
+	Precision int    // decimals of float fields
+	Header    string // CSV header, if empty the name and unit, like alt[m]
+	// decimals of float fields in the APRS string, if not 0 and different
+	// from Precision
+	AprsPrecision int
+}
+
+func (f Field) header() string {
+	switch {
+	case f.Header != "":
+		return f.Header
+	case f.Unit != "":
+		return f.Name + "[" + f.Unit + "]"
+	default:
+		return f.Name
+	}
+}
+
+// format returns the value as a string, empty if not set
+func (f Field) format(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', f.Precision, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case string:
+		return v
+	}
+	return ""
+}
+
+// check returns an error if v is not of the field type
+func (f Field) check(v any) error {
+	ok := false
+	switch f.Type {
+	case FloatField:
+		_, ok = v.(float64)
+	case IntField:
+		_, ok = v.(int)
+	case BoolField:
+		_, ok = v.(bool)
+	case StringField:
+		_, ok = v.(string)
+	}
+	if !ok {
+		return fmt.Errorf("Bad value type for telemetry field %s: %T", f.Name, v)
+	}
+	return nil
+}
+
+// registry keeps the fields, their values and validity and their order
+// in the radio string and the CSV datalog
+type registry struct {
+	fields   map[string]Field
+	values   map[string]any
+	validity map[string]Validity
+	aprs     []string
+	csv      []string
+}
+
+func newRegistry() registry {
+	return registry{
+		fields:   map[string]Field{},
+		values:   map[string]any{},
+		validity: map[string]Validity{},
+	}
+}
+
+// register adds a field at the end of the radio string and/or CSV
+func (r *registry) register(f Field, aprs bool, csv bool) error {
+	if f.Name == "" || strings.ContainsAny(f.Name, ", ") {
+		return fmt.Errorf("Bad telemetry field name: %q", f.Name)
+	}
+	if _, ok := r.fields[f.Name]; ok {
+		return fmt.Errorf("Telemetry field %s already registered", f.Name)
+	}
+	r.fields[f.Name] = f
+	if aprs {
+		r.aprs = append(r.aprs, f.Name)
+	}
+	if csv {
+		r.csv = append(r.csv, f.Name)
+	}
+	return nil
+}
+
+// set changes the value of a field, a new value is valid
+func (r *registry) set(name string, v any) error {
+	f, ok := r.fields[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownField, name)
+	}
+	if err := f.check(v); err != nil {
+		return err
+	}
+	r.values[name] = v
+	delete(r.validity, name)
+	return nil
+}
+
+// valid returns the validity of a field, NaN values are invalid
+func (r *registry) valid(name string) Validity {
+	if v, ok := r.values[name].(float64); ok && math.IsNaN(v) {
+		return Invalid
+	}
+	return r.validity[name]
+}
+
+// order checks the names of a field list and returns a copy
+func (r *registry) order(names []string) ([]string, error) {
+	for _, name := range names {
+		if _, ok := r.fields[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+	}
+	return append([]string(nil), names...), nil
+}
+
+// formatted returns the value of a field as a string, invalid values
+// are empty, with a column for each column of the CSV header
+func (r *registry) formatted(name string) string {
+	f := r.fields[name]
+	if r.valid(name) == Invalid {
+		return strings.Repeat(",", strings.Count(f.header(), ","))
+	}
+	return f.format(r.values[name])
+}
+
+// aprsFormatted returns the value of a field for the APRS string, like
+// formatted but with the APRS precision
+func (r *registry) aprsFormatted(name string) string {
+	f := r.fields[name]
+	if f.AprsPrecision == 0 || r.valid(name) == Invalid {
+		return r.formatted(name)
+	}
+	f.Precision = f.AprsPrecision
+	return f.format(r.values[name])
+}
+
+// Register adds a field, at the end of the radio string and CSV datalog
+// if their field lists are not configured
+func (t *telemetry) Register(f Field) error {
+	return t.fields.register(f, true, true)
+}
+
+// Set changes the value of a registered field
+func (t *telemetry) Set(name string, v any) error {
+	if err := t.fields.set(name, v); err != nil {
+		return err
+	}
+	t.setStatus()
+	return nil
+}
+
+// SetAprsFields sets the fields of the radio string, in order
+func (t *telemetry) SetAprsFields(names []string) error {
+	order, err := t.fields.order(names)
+	if err != nil {
+		return err
+	}
+	t.fields.aprs = order
+	return nil
+}
+
+// SetCsvFields sets the fields of the CSV datalog, in order
+func (t *telemetry) SetCsvFields(names []string) error {
+	order, err := t.fields.order(names)
+	if err != nil {
+		return err
+	}
+	t.fields.csv = order
+	return nil
+}
+
+// CsvHeader returns the CSV datalog header
+func (t *telemetry) CsvHeader() string {
+	headers := make([]string, 0, len(t.fields.csv))
+	for _, name := range t.fields.csv {
+		headers = append(headers, t.fields.fields[name].header())
+	}
+	return strings.Join(headers, ",")
+}
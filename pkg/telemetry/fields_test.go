@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestFields(t *testing.T) {
+	telem := New("TEST", "Test", "/")
+	if h := telem.CsvHeader(); h != "date,time,lat,ns,lon,ew,alt[m],vbat[V],tin[C],tout[C],baro[mbar],"+
+		"hdg[deg],spd[kn],sats,arate[m/s],pwr,gps_health,seq,uptime[s],boot,status" {
+		t.Errorf("Bad default CSV header: %s", h)
+	}
+
+	// new sensor
+	err := telem.Register(Field{Name: "hum", Key: "H", Unit: "%", Type: FloatField, Precision: 1})
+	if err != nil {
+		t.Fatalf("Error registering field: %v", err)
+	}
+	if err := telem.Register(Field{Name: "hum", Type: IntField}); err == nil {
+		t.Error("Duplicated field registered")
+	}
+	if err := telem.Register(Field{Name: "a,b", Type: IntField}); err == nil {
+		t.Error("Bad field name registered")
+	}
+	telem.Update(position.Position{Lat: 43.5, Lon: -5.6, Alt: 100}, 0, 0, 5, "OK", 4.12, 1000, 20, 15, false)
+	if err := telem.Set("hum", 45.25); err != nil {
+		t.Fatalf("Error setting field: %v", err)
+	}
+	if err := telem.Set("hum", 45); err == nil {
+		t.Error("Bad field type accepted")
+	}
+	if err := telem.Set("foo", 1.0); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Unknown field set: %v", err)
+	}
+	// the battery voltage with 1 decimal in the APRS string, 2 in the CSV
+	if aprs := telem.AprsString(); !strings.Contains(aprs, "/V=4.1/") || !strings.Contains(aprs, "/GH=OK/SEQ=1/") ||
+		!strings.Contains(aprs, "/BOOT=0/ST=0/H=45.2/Test - L") {
+		t.Errorf("Field not in APRS string: %s", aprs)
+	}
+	if csv := telem.CsvString(); !strings.Contains(csv, ",100.0,4.12,") || !strings.Contains(csv, ",L,OK,1,") ||
+		!strings.HasSuffix(csv, ",0,45.2") {
+		t.Errorf("Field not in CSV: %s", csv)
+	}
+	if h := telem.CsvHeader(); !strings.HasSuffix(h, ",gps_health,seq,uptime[s],boot,status,hum[%]") {
+		t.Errorf("Field not in CSV header: %s", h)
+	}
+
+	// configured order
+	if err := telem.SetAprsFields([]string{"alt", "hum", "sats"}); err != nil {
+		t.Fatalf("Error setting APRS fields: %v", err)
+	}
+	if aprs := telem.AprsString(); !strings.HasPrefix(aprs, "$$TEST!4330.00N/00536.00WOA=100.0/H=45.2/SATS=5/Test - L") {
+		t.Errorf("Bad APRS string field order: %s", aprs)
+	}
+	if err := telem.SetCsvFields([]string{"time", "hum"}); err != nil {
+		t.Fatalf("Error setting CSV fields: %v", err)
+	}
+	if h, csv := telem.CsvHeader(), telem.CsvString(); h != "time,hum[%]" || !strings.HasSuffix(csv, ",45.2") {
+		t.Errorf("Bad CSV field order: %s %s", h, csv)
+	}
+	if err := telem.SetCsvFields([]string{"time", "foo"}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Unknown field in CSV: %v", err)
+	}
+}
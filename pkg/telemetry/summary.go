@@ -0,0 +1,158 @@
+package telemetry
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flight summary: the extremes of the flight, sent periodically so the
+// burst altitude and the coldest temperatures are known even if their
+// packets were lost
+
+// Extremes are the running flight statistics, NaN while unknown
+type Extremes struct {
+	MaxAlt     float64   // m
+	MaxAltTime time.Time // zero while unknown
+	MinTin     float64   // C
+	MinTout    float64   // C
+	MinVbat    float64   // V
+	MaxAscent  float64   // m/s
+	MaxDescent float64   // m/s, positive
+}
+
+func NewExtremes() Extremes {
+	nan := math.NaN()
+	return Extremes{MaxAlt: nan, MinTin: nan, MinTout: nan, MinVbat: nan, MaxAscent: nan, MaxDescent: nan}
+}
+
+// more updates a maximum with v, NaN values are ignored, an unknown
+// (NaN) maximum is always updated
+func more(hi *float64, v float64) bool {
+	if math.IsNaN(v) || v <= *hi {
+		return false
+	}
+	*hi = v
+	return true
+}
+
+// less updates a minimum with v, like more
+func less(lo *float64, v float64) bool {
+	if math.IsNaN(v) || v >= *lo {
+		return false
+	}
+	*lo = v
+	return true
+}
+
+// Update adds the values measured at t, invalid values are NaN.
+// Returns true if any of the extremes changed
+func (e *Extremes) Update(t time.Time, alt float64, tin float64, tout float64, vbat float64, arate float64) bool {
+	changed := false
+	if more(&e.MaxAlt, alt) {
+		e.MaxAltTime = t.UTC()
+		changed = true
+	}
+	changed = less(&e.MinTin, tin) || changed
+	changed = less(&e.MinTout, tout) || changed
+	changed = less(&e.MinVbat, vbat) || changed
+	switch {
+	case arate > 0:
+		changed = more(&e.MaxAscent, arate) || changed
+	case arate < 0:
+		changed = more(&e.MaxDescent, -arate) || changed
+	}
+	return changed
+}
+
+// summary value, empty if unknown
+func summaryValue(v float64, precision int) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+// summaryValues returns the formatted extremes, in the summary order,
+// unknown values are empty
+func summaryValues(e Extremes) []string {
+	maxAltTime := ""
+	if !e.MaxAltTime.IsZero() {
+		maxAltTime = e.MaxAltTime.Format(time.TimeOnly)
+	}
+	return []string{
+		summaryValue(e.MaxAlt, 1),
+		maxAltTime,
+		summaryValue(e.MinTin, 1),
+		summaryValue(e.MinTout, 1),
+		summaryValue(e.MinVbat, 2),
+		summaryValue(e.MaxAscent, 1),
+		summaryValue(e.MaxDescent, 1),
+	}
+}
+
+// SummaryString returns the flight summary packet, with the mission
+// separator: $$ID!SUM/AMAX=m/AMAXT=HH:MM:SS/TIMIN=C/TOMIN=C/VMIN=V/ARMAX=m/s/DRMAX=m/s,
+// unknown values are empty
+func (t *telemetry) SummaryString(e Extremes) string {
+	keys := []string{"AMAX=", "AMAXT=", "TIMIN=", "TOMIN=", "VMIN=", "ARMAX=", "DRMAX="}
+	fields := []string{"$$" + t.id + "!SUM"}
+	for i, v := range summaryValues(e) {
+		fields = append(fields, keys[i]+v)
+	}
+	return strings.Join(fields, t.sep) + "\n"
+}
+
+// UkhasSummary returns the flight summary as an UKHAS sentence, with SUM
+// instead of the counter so it's not taken as a telemetry sentence:
+// $$CALL,SUM,max_alt,max_alt_time,min_tin,min_tout,min_vbat,max_ascent,max_descent*CRC16
+func (t *telemetry) UkhasSummary(e Extremes) string {
+	sentence := strings.Join(append([]string{t.id, "SUM"}, summaryValues(e)...), ",")
+	return fmt.Sprintf("$$%s*%04X\n", sentence, CRC16([]byte(sentence)))
+}
+
+// max length of the APRS status report text, without timestamp
+const aprsStatusLen = 62
+
+// AprsSummary returns the flight summary as an APRS status report
+// information field, like >Max 31234m 07:00:00 TI=5.0 TO=-58.2 V=3.90
+// AR=5.8 DR=35.2 (temperatures in C, ascent and descent rates in m/s).
+// The values that don't fit in a status report are left out
+func (t *telemetry) AprsSummary(e Extremes) string {
+	var parts []string
+	if !math.IsNaN(e.MaxAlt) {
+		alt := fmt.Sprintf("Max %.0fm", e.MaxAlt)
+		if !e.MaxAltTime.IsZero() {
+			alt += " " + e.MaxAltTime.Format(time.TimeOnly)
+		}
+		parts = append(parts, alt)
+	}
+	for _, v := range []struct {
+		key       string
+		value     float64
+		precision int
+	}{
+		{"TI", e.MinTin, 1},
+		{"TO", e.MinTout, 1},
+		{"V", e.MinVbat, 2},
+		{"AR", e.MaxAscent, 1},
+		{"DR", e.MaxDescent, 1},
+	} {
+		if !math.IsNaN(v.value) {
+			parts = append(parts, v.key+"="+summaryValue(v.value, v.precision))
+		}
+	}
+	status := ""
+	for _, p := range parts {
+		if len(status)+len(p)+1 > aprsStatusLen {
+			break
+		}
+		if status != "" {
+			status += " "
+		}
+		status += p
+	}
+	return ">" + status
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtremes(t *testing.T) {
+	e := NewExtremes()
+	start := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	if !e.Update(start, 545, 20, 15, 4.1, math.NaN()) {
+		t.Error("First values not extremes")
+	}
+	e.Update(start.Add(time.Hour), 31234.5, 5, -58.2, 3.9, 5.8)
+	// burst, sensor failures are ignored
+	e.Update(start.Add(time.Hour+time.Minute), 30000, math.NaN(), -50, math.NaN(), -35.2)
+	if e.Update(start.Add(time.Hour*2), 600, 10, -5, 4.0, -5) {
+		t.Error("Changed extremes without new extremes")
+	}
+	if e.MaxAlt != 31234.5 || !e.MaxAltTime.Equal(start.Add(time.Hour)) || e.MinTin != 5 || e.MinTout != -58.2 ||
+		e.MinVbat != 3.9 || e.MaxAscent != 5.8 || e.MaxDescent != 35.2 {
+		t.Errorf("Bad extremes: %+v", e)
+	}
+
+	telem := New("EA1IDZ-11", "Test", "/")
+	if s := telem.SummaryString(e); s != "$$EA1IDZ-11!SUM/AMAX=31234.5/AMAXT=07:00:00/TIMIN=5.0/TOMIN=-58.2/"+
+		"VMIN=3.90/ARMAX=5.8/DRMAX=35.2\n" {
+		t.Errorf("Bad summary string: %q", s)
+	}
+	ukhas := telem.UkhasSummary(e)
+	star := strings.LastIndex(ukhas, "*")
+	if !strings.HasPrefix(ukhas, "$$EA1IDZ-11,SUM,31234.5,07:00:00,5.0,-58.2,3.90,5.8,35.2*") ||
+		ukhas[star+1:] != fmt.Sprintf("%04X\n", CRC16([]byte(ukhas[2:star]))) {
+		t.Errorf("Bad UKHAS summary: %q", ukhas)
+	}
+	if s := telem.AprsSummary(e); s != ">Max 31234m 07:00:00 TI=5.0 TO=-58.2 V=3.90 AR=5.8 DR=35.2" {
+		t.Errorf("Bad APRS summary: %q", s)
+	}
+	// longest values, in a status report
+	long := Extremes{MaxAlt: 123456, MaxAltTime: start, MinTin: -100.5, MinTout: -100.5, MinVbat: 12.6,
+		MaxAscent: 99.9, MaxDescent: 150.5}
+	if s := telem.AprsSummary(long); len(s)-1 > aprsStatusLen || !strings.HasPrefix(s, ">Max 123456m 06:00:00 TI=-100.5") {
+		t.Errorf("Bad APRS summary length %d: %q", len(s)-1, s)
+	}
+
+	// unknown values
+	e = NewExtremes()
+	e.Update(start, math.NaN(), -1.5, math.NaN(), math.NaN(), 0)
+	if s := telem.SummaryString(e); s != "$$EA1IDZ-11!SUM/AMAX=/AMAXT=/TIMIN=-1.5/TOMIN=/VMIN=/ARMAX=/DRMAX=\n" {
+		t.Errorf("Bad summary string with unknown values: %q", s)
+	}
+	if s := telem.AprsSummary(e); s != ">TI=-1.5" {
+		t.Errorf("Bad APRS summary with unknown values: %q", s)
+	}
+}
@@ -2,20 +2,18 @@ package telemetry
 
 import (
 	"fmt"
-	"math"
 	"strings"
 	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
 )
 
 type Telemetry interface {
-	Update(lat float64,
-		ns string,
-		lon float64,
-		ew string,
-		alt float64,
+	Update(pos position.Position,
 		hdg float64,
 		spd float64,
 		sats int,
+		gpsHealth string,
 		vbat float64,
 		baro float64,
 		tin float64,
@@ -23,150 +21,219 @@ type Telemetry interface {
 		hpwr bool)
 	AprsString() string
 	CsvString() string
+	UkhasString() string
+	Binary() []byte
+	AprsPosition() string
+	AprsTelemetry() string
+	AprsDefinitions() []string
+	SetUkhasFields([]string) error
+	Register(Field) error
+	Set(name string, v any) error
+	SetValidity(v Validity, names ...string) error
+	Validity(name string) Validity
+	SetAprsFields([]string) error
+	SetCsvFields([]string) error
+	CsvHeader() string
+	SetBoot(int)
+	CsvSchema() string
+	JsonSchema() string
+	JsonString() string
+	SummaryString(Extremes) string
+	UkhasSummary(Extremes) string
+	BinarySummary(Extremes) []byte
+	AprsSummary(Extremes) string
+}
+
+// built in fields
+var builtinFields = []Field{
+	{Name: "date", Type: StringField},
+	{Name: "time", Type: StringField},
+	{Name: "pos", Type: StringField, Header: "lat,ns,lon,ew"},
+	{Name: "gps", Key: "GPS", Type: StringField},
+	{Name: "alt", Key: "A", Unit: "m", Type: FloatField, Precision: 1},
+	{Name: "hdg", Unit: "deg", Type: FloatField, Precision: 1},
+	{Name: "spd", Unit: "kn", Type: FloatField, Precision: 1},
+	{Name: "sats", Key: "SATS", Type: IntField},
+	{Name: "gps_health", Key: "GH", Type: StringField},
+	{Name: "vbat", Key: "V", Unit: "V", Type: FloatField, Precision: 2, AprsPrecision: 1},
+	{Name: "baro", Key: "P", Unit: "mbar", Type: FloatField, Precision: 1},
+	{Name: "tin", Key: "TI", Unit: "C", Type: FloatField, Precision: 1},
+	{Name: "tout", Key: "TO", Unit: "C", Type: FloatField, Precision: 1},
+	{Name: "arate", Key: "AR", Unit: "m/s", Type: FloatField, Precision: 1},
+	{Name: "pwr", Type: StringField},
+	{Name: "seq", Key: "SEQ", Type: IntField},
+	{Name: "uptime", Key: "UP", Unit: "s", Type: IntField},
+	{Name: "boot", Key: "BOOT", Type: IntField},
+	{Name: "status", Key: "ST", Type: IntField},
 }
 
+// default field lists of the radio string (between the position and the
+// message) and the CSV datalog
+var (
+	DefaultAprsFields = []string{"hdg", "spd", "alt", "vbat", "baro", "tin", "tout",
+		"date", "time", "gps", "sats", "arate", "gps_health", "seq", "uptime", "boot", "status"}
+	DefaultCsvFields = []string{"date", "time", "pos", "alt", "vbat", "tin", "tout",
+		"baro", "hdg", "spd", "sats", "arate", "pwr", "gps_health", "seq", "uptime", "boot", "status"}
+)
+
+// start of the process, for the uptime
+var processStart = time.Now()
+
 type telemetry struct {
-	id       string
-	msg      string
-	lat      float64
-	ns       string
-	lon      float64
-	ew       string
-	alt      float64
-	hdg      float64
-	spd      float64
-	sats     int
-	vbat     float64
-	baro     float64
-	tin      float64
-	tout     float64
-	arate    float64
-	date     string
-	time     string
-	sep      string
-	dateTime time.Time
-	hpwr     bool
+	id        string
+	msg       string
+	pos       position.Position
+	hdg       float64
+	spd       float64
+	sats      int
+	gpsHealth string
+	vbat      float64
+	baro      float64
+	tin       float64
+	tout      float64
+	date      string
+	time      string
+	sep       string
+	dateTime  time.Time
+	hpwr      bool
+	// sequence number of the telemetry frames, also the UKHAS sentence
+	// counter, and the optional UKHAS fields
+	counter     int
+	uptime      time.Duration
+	boot        int
+	ukhasFields []string
+	fields      registry
 }
 
 func New(i string, m string, s string) Telemetry {
 	dt := time.Now().UTC()
 
 	// default values
-	return &telemetry{
-		id:    i,
-		msg:   m,
-		sep:   s,
-		lat:   0.0,
-		ns:    "N",
-		lon:   0.0,
-		ew:    "W",
-		alt:   0.0,
-		hdg:   0.0,
-		spd:   0.0,
-		sats:  0,
-		vbat:  0.0,
-		baro:  0.0,
-		tin:   0.0,
-		tout:  0.0,
-		arate: 0.0,
-		date:  fmt.Sprintf("%02d-%02d-%d", dt.Day(), dt.Month(), dt.Year()),
-		time:  fmt.Sprintf("%02d:%02d:%02d", dt.Hour(), dt.Minute(), dt.Second()),
-		hpwr:  false,
+	t := telemetry{
+		id:          i,
+		msg:         m,
+		sep:         s,
+		pos:         position.Position{},
+		hdg:         0.0,
+		spd:         0.0,
+		sats:        0,
+		gpsHealth:   "",
+		vbat:        0.0,
+		baro:        0.0,
+		tin:         0.0,
+		tout:        0.0,
+		date:        fmt.Sprintf("%02d-%02d-%d", dt.Day(), dt.Month(), dt.Year()),
+		time:        fmt.Sprintf("%02d:%02d:%02d", dt.Hour(), dt.Minute(), dt.Second()),
+		dateTime:    dt,
+		hpwr:        false,
+		ukhasFields: DefaultUkhasFields,
+		fields:      newRegistry(),
+	}
+	for _, f := range builtinFields {
+		t.fields.register(f, false, false)
 	}
+	// vertical speed, set by the mission with Set
+	t.fields.values["arate"] = 0.0
+	t.fields.aprs = DefaultAprsFields
+	t.fields.csv = DefaultCsvFields
+	t.setFields()
+	return &t
 }
 
+// setFields updates the built in fields values
+func (t *telemetry) setFields() {
+	t.fields.values["date"] = t.date
+	t.fields.values["time"] = t.time
+	t.fields.values["pos"] = t.pos.CSV()
+	t.fields.values["gps"] = t.pos.Decimal()
+	t.fields.values["alt"] = t.pos.Alt
+	t.fields.values["hdg"] = t.hdg
+	t.fields.values["spd"] = t.spd
+	t.fields.values["sats"] = t.sats
+	t.fields.values["gps_health"] = t.gpsHealth
+	t.fields.values["vbat"] = t.vbat
+	t.fields.values["baro"] = t.baro
+	t.fields.values["tin"] = t.tin
+	t.fields.values["tout"] = t.tout
+	t.fields.values["seq"] = t.counter
+	t.fields.values["uptime"] = int(t.uptime.Seconds())
+	t.fields.values["boot"] = t.boot
+	t.fields.values["pwr"] = "L"
+	if t.hpwr {
+		t.fields.values["pwr"] = "H"
+	}
+	t.setStatus()
+}
+
+// Update sets the sensor values of a new telemetry frame, invalid values
+// are NaN, and the validity of the other fields can be set after it
 func (t *telemetry) Update(
-	lat float64,
-	ns string,
-	lon float64,
-	ew string,
-	alt float64,
+	pos position.Position,
 	hdg float64,
 	spd float64,
 	sats int,
+	gpsHealth string,
 	vbat float64,
 	baro float64,
 	tin float64,
 	tout float64,
 	hpwr bool) {
 
-	// save old altitude for ascension rate
-	oldAlt := t.alt
-
 	// update fields
-	t.lat = lat
-	t.ns = ns
-	t.lon = lon
-	t.ew = ew
-	t.alt = alt
+	t.pos = pos
 	t.hdg = hdg
 	t.spd = spd
 	t.sats = sats
+	t.gpsHealth = gpsHealth
 	t.vbat = vbat
 	t.baro = baro
 	t.tin = tin
 	t.tout = tout
 	t.hpwr = hpwr
-
-	// save old datetime
-	oldDateTime := t.dateTime
+	t.counter++
+	t.uptime = time.Since(processStart)
+	// new values are valid, NaN values invalid
+	clear(t.fields.validity)
 
 	// update packet date
 	t.dateTime = time.Now().UTC()
 	t.date = fmt.Sprintf("%02d-%02d-%d", t.dateTime.Day(), t.dateTime.Month(), t.dateTime.Year())
 	t.time = fmt.Sprintf("%02d:%02d:%02d", t.dateTime.Hour(), t.dateTime.Minute(), t.dateTime.Second())
 
-	// calculate ascension rate
-	deltaTime := t.dateTime.Sub(oldDateTime)
-	if deltaTime.Milliseconds() != 0 {
-		t.arate = (t.alt - oldAlt) /
-			(float64(deltaTime.Milliseconds()) / 1000.0)
-	} else {
-		t.arate = 0.0
-	}
+	t.setFields()
 }
 
-func (t *telemetry) AprsString() string {
-	// gen APRS coordinates
-	coords := fmt.Sprintf(
-		"%07.2f%s%s%08.2f%s",
-		t.lat, t.ns, t.sep, t.lon, t.ew)
+// SetBoot sets the boot counter of the mission
+func (t *telemetry) SetBoot(boot int) {
+	t.boot = boot
+	t.setFields()
+}
 
+// APRS string coordinates without a fix, all the digits blanked like the
+// APRS position ambiguity, so they can't be taken for a position
+const (
+	AprsNoLat = "    .  N"
+	AprsNoLon = "     .  E"
+)
+
+func (t *telemetry) AprsString() string {
 	// gen APRS string
 	aprs := "$$"
 	aprs += t.id
 	aprs += "!"
-	aprs += coords
+	if t.fields.valid("pos") == Invalid {
+		aprs += AprsNoLat + t.sep + AprsNoLon
+	} else {
+		aprs += t.pos.APRS(t.sep)
+	}
 	aprs += "O"
-	aprs += fmt.Sprintf("%.1f", t.hdg)
-	aprs += t.sep
-	aprs += fmt.Sprintf("%.1f", t.spd)
-	aprs += t.sep
-	aprs += fmt.Sprintf("A=%.1f", t.alt)
-	aprs += t.sep
-	aprs += fmt.Sprintf("V=%.1f", t.vbat)
-	aprs += t.sep
-	aprs += fmt.Sprintf("P=%.1f", t.baro)
-	aprs += t.sep
-	aprs += fmt.Sprintf("TI=%.1f", t.tin)
-	aprs += t.sep
-	aprs += fmt.Sprintf("TO=%.1f", t.tout)
-	aprs += t.sep
-	aprs += t.date
-	aprs += t.sep
-	aprs += t.time
-	aprs += t.sep
-	aprs += fmt.Sprintf(
-		"GPS=%09.6f%s,%010.6f%s",
-		decLat(t.lat),
-		t.ns,
-		decLon(t.lon),
-		t.ew)
-	aprs += t.sep
-	aprs += fmt.Sprintf("SATS=%d", t.sats)
-	aprs += t.sep
-	aprs += fmt.Sprintf("AR=%.1f", t.arate)
-	aprs += t.sep
+	for _, name := range t.fields.aprs {
+		if key := t.fields.fields[name].Key; key != "" {
+			aprs += key + "="
+		}
+		aprs += t.fields.aprsFormatted(name)
+		aprs += t.sep
+	}
 	aprs += strings.ReplaceAll(t.msg, "\n", " - ")
 	aprs += func() string {
 		if t.hpwr {
@@ -182,41 +249,9 @@ func (t *telemetry) AprsString() string {
 
 func (t *telemetry) CsvString() string {
 	// gen CSV string
-	csv := ""
-	csv += t.date + ","
-	csv += t.time + ","
-	csv += fmt.Sprintf("%f", decLat(t.lat)) + ","
-	csv += t.ns + ","
-	csv += fmt.Sprintf("%f", decLon(t.lon)) + ","
-	csv += t.ew + ","
-	csv += fmt.Sprintf("%.1f", t.alt) + ","
-	csv += fmt.Sprintf("%.2f", t.vbat) + ","
-	csv += fmt.Sprintf("%.1f", t.tin) + ","
-	csv += fmt.Sprintf("%.1f", t.tout) + ","
-	csv += fmt.Sprintf("%.1f", t.baro) + ","
-	csv += fmt.Sprintf("%.1f", t.hdg) + ","
-	csv += fmt.Sprintf("%.1f", t.spd) + ","
-	csv += fmt.Sprintf("%d", t.sats) + ","
-	csv += fmt.Sprintf("%.1f", t.arate) + ","
-	csv += func() string {
-		if t.hpwr {
-			return "H"
-		} else {
-			return "L"
-		}
-	}()
-
-	return csv
-}
-
-func decLat(lat float64) float64 {
-	degrees := math.Trunc(lat / 100.0)
-	fraction := (lat - (degrees * 100.0)) / 60.0
-	return degrees + fraction
-}
-
-func decLon(lon float64) float64 {
-	degrees := math.Trunc(lon / 100.0)
-	fraction := (lon - (degrees * 100.0)) / 60.0
-	return degrees + fraction
+	values := make([]string, 0, len(t.fields.csv))
+	for _, name := range t.fields.csv {
+		values = append(values, t.fields.formatted(name))
+	}
+	return strings.Join(values, ",")
 }
@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
 )
 
 func TestTelemetry(t *testing.T) {
 	telem := New("TEST", "Test telemetry message", "/")
 
-	telem.Update(4332.944, "N", 539.783, "W", 0.0, 0.0, 0.0, 0, 0.0, 1019.5, 15.5, 5.4, false)
+	pos := position.FromNMEA(4332.944, "N", 539.783, "W", 0.0, time.Time{})
+	telem.SetBoot(7)
+	telem.Update(pos, 0.0, 0.0, 0, "OK", 0.0, 1019.5, 15.5, 5.4, false)
 
 	aprs := telem.AprsString()
 	fmt.Println(aprs)
@@ -17,4 +22,20 @@ func TestTelemetry(t *testing.T) {
 	if !strings.Contains(aprs, "P=1019.5") {
 		t.Errorf("Problem with generated APRS string: %s", aprs)
 	}
+	if !strings.HasPrefix(aprs, "$$TEST!4332.94N/00539.78WO") ||
+		!strings.Contains(aprs, "GPS=43.549067N,005.663050W") {
+		t.Errorf("Problem with APRS coordinates: %s", aprs)
+	}
+
+	if !strings.Contains(aprs, "/GH=OK/SEQ=1/UP=") || !strings.Contains(aprs, "/BOOT=7/") {
+		t.Errorf("Problem with APRS GPS health, sequence and boot: %s", aprs)
+	}
+
+	csv := telem.CsvString()
+	if !strings.Contains(csv, ",43.549067,N,5.663050,W,") {
+		t.Errorf("Problem with CSV coordinates: %s", csv)
+	}
+	if !strings.Contains(csv, ",L,OK,1,") || !strings.HasSuffix(csv, ",7,0") {
+		t.Errorf("Problem with CSV GPS health, sequence and boot: %s", csv)
+	}
 }
@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultUkhasFields are sent if no field list is configured
+var DefaultUkhasFields = []string{"sats", "vbat", "tin", "tout", "baro", "arate", "uptime", "boot"}
+
+// SetUkhasFields sets the optional fields of the UKHAS sentence, in order,
+// any registered field without commas in its values (not pos or gps)
+// can be used
+func (t *telemetry) SetUkhasFields(fields []string) error {
+	order, err := t.fields.order(fields)
+	if err != nil {
+		return err
+	}
+	for _, name := range order {
+		if strings.Contains(t.fields.formatted(name), ",") {
+			return fmt.Errorf("Telemetry field %s has commas, can't be sent in UKHAS sentences", name)
+		}
+	}
+	t.ukhasFields = order
+	return nil
+}
+
+// UkhasString returns the telemetry as an UKHAS sentence:
+// $$CALL,counter,time,lat,lon,alt,fields...*CRC16, invalid values are empty
+func (t *telemetry) UkhasString() string {
+	lat, lon, alt := "", "", ""
+	if t.fields.valid("pos") != Invalid {
+		lat, lon = fmt.Sprintf("%.6f", t.pos.Lat), fmt.Sprintf("%.6f", t.pos.Lon)
+	}
+	if t.fields.valid("alt") != Invalid {
+		alt = fmt.Sprintf("%.0f", t.pos.Alt)
+	}
+	fields := []string{
+		t.id,
+		fmt.Sprintf("%d", t.counter),
+		t.time,
+		lat,
+		lon,
+		alt,
+	}
+	for _, f := range t.ukhasFields {
+		fields = append(fields, t.fields.formatted(f))
+	}
+	sentence := strings.Join(fields, ",")
+	return fmt.Sprintf("$$%s*%04X\n", sentence, CRC16([]byte(sentence)))
+}
+
+// CRC16 calculates the CRC16-CCITT (0x1021 polynomial, 0xFFFF initial
+// value) used by UKHAS sentences and binary packets
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
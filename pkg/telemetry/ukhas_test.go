@@ -0,0 +1,53 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestCRC16(t *testing.T) {
+	if crc := CRC16([]byte("123456789")); crc != 0x29B1 {
+		t.Errorf("Bad CRC16: %04X", crc)
+	}
+}
+
+func TestUkhas(t *testing.T) {
+	telem := New("TEST", "Test telemetry message", "/")
+	pos := position.FromNMEA(4332.944, "N", 539.783, "W", 545.4, time.Time{})
+	telem.SetBoot(2)
+	telem.Update(pos, 90.0, 5.0, 8, "OK", 4.12, 1019.5, 15.5, 5.4, false)
+
+	ukhas := telem.UkhasString()
+	if !strings.HasPrefix(ukhas, "$$TEST,1,") ||
+		!strings.Contains(ukhas, ",43.549067,-5.663050,545,8,4.12,15.5,5.4,1019.5,0.0,") ||
+		!strings.Contains(ukhas, ",2*") {
+		t.Errorf("Problem with UKHAS sentence: %s", ukhas)
+	}
+	// checksum
+	star := strings.LastIndex(ukhas, "*")
+	if ukhas[star+1:] != fmt.Sprintf("%04X\n", CRC16([]byte(ukhas[2:star]))) {
+		t.Errorf("Bad UKHAS checksum: %s", ukhas)
+	}
+
+	// configured fields and counter
+	if err := telem.SetUkhasFields([]string{"gps_health", "pwr", "hdg"}); err != nil {
+		t.Fatalf("Error setting UKHAS fields: %v", err)
+	}
+	telem.Update(pos, 90.0, 5.0, 8, "OK", 4.12, 1019.5, 15.5, 5.4, true)
+	ukhas = telem.UkhasString()
+	if !strings.HasPrefix(ukhas, "$$TEST,2,") || !strings.Contains(ukhas, ",545,OK,H,90.0*") {
+		t.Errorf("Problem with UKHAS fields: %s", ukhas)
+	}
+	if err := telem.SetUkhasFields([]string{"sats", "foo"}); err == nil {
+		t.Error("Unknown UKHAS field accepted")
+	}
+	for _, f := range []string{"pos", "gps"} {
+		if err := telem.SetUkhasFields([]string{"sats", f}); err == nil {
+			t.Errorf("UKHAS field with commas accepted: %s", f)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package telemetry
+
+import (
+	"fmt"
+)
+
+// Validity of the telemetry values. Invalid values, like NaN or the
+// fields of a failed sensor, are empty in the radio strings and CSV,
+// null in JSON and 0 in binary packets. Stale values, like a position
+// held from an older GPS fix, are sent as they are. Both are flagged
+// in the status field.
+
+// Validity is the state of a telemetry value
+type Validity int
+
+const (
+	Valid   Validity = iota // new value from the sensor
+	Stale                   // last good value, no new data
+	Invalid                 // no data, the sensor failed
+)
+
+func (v Validity) String() string {
+	switch v {
+	case Valid:
+		return "valid"
+	case Stale:
+		return "stale"
+	default:
+		return "invalid"
+	}
+}
+
+// StatusFields are the fields of the status bitfield, in bit order
+var StatusFields = []string{"pos", "alt", "hdg", "spd", "vbat", "baro", "tin", "tout", "arate"}
+
+// bit of the first stale field
+const statusStaleShift = 16
+
+// Status is the validity bitfield of the status field: bit i is set if
+// StatusFields[i] is invalid, bit i+16 if it's stale
+type Status uint32
+
+// Validity returns the validity of a status field, valid if unknown
+func (s Status) Validity(name string) Validity {
+	for i, f := range StatusFields {
+		if f != name {
+			continue
+		}
+		switch {
+		case s&(1<<i) != 0:
+			return Invalid
+		case s&(1<<(i+statusStaleShift)) != 0:
+			return Stale
+		}
+	}
+	return Valid
+}
+
+// SetValidity changes the validity of registered fields, until their
+// next value
+func (t *telemetry) SetValidity(v Validity, names ...string) error {
+	for _, name := range names {
+		if _, ok := t.fields.fields[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+	}
+	for _, name := range names {
+		if v == Valid {
+			delete(t.fields.validity, name)
+		} else {
+			t.fields.validity[name] = v
+		}
+	}
+	t.setStatus()
+	return nil
+}
+
+// Validity returns the validity of a registered field
+func (t *telemetry) Validity(name string) Validity {
+	return t.fields.valid(name)
+}
+
+// status returns the status bitfield
+func (t *telemetry) status() Status {
+	var s Status
+	for i, name := range StatusFields {
+		switch t.fields.valid(name) {
+		case Invalid:
+			s |= 1 << i
+		case Stale:
+			s |= 1 << (i + statusStaleShift)
+		}
+	}
+	return s
+}
+
+// setStatus updates the status field
+func (t *telemetry) setStatus() {
+	t.fields.values["status"] = int(t.status())
+}
@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ladecadence/EkiGo/pkg/position"
+)
+
+func TestValidity(t *testing.T) {
+	telem := New("TEST", "Test", "/")
+	pos := position.FromNMEA(4332.944, "N", 539.783, "W", 545.4, time.Time{})
+	telem.Update(pos, 90.0, 5.0, 8, "OK", 4.12, 1019.5, math.NaN(), 5.4, false)
+	if err := telem.SetValidity(Invalid, "pos", "gps", "alt"); err != nil {
+		t.Fatalf("Error setting validity: %v", err)
+	}
+	if err := telem.SetValidity(Stale, "foo"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Unknown field validity set: %v", err)
+	}
+	if v := telem.Validity("tin"); v != Invalid {
+		t.Errorf("NaN value %v", v)
+	}
+
+	// invalid position (bit 0), altitude (1) and internal temperature (6)
+	aprs := telem.AprsString()
+	if !strings.HasPrefix(aprs, "$$TEST!    .  N/     .  EO") {
+		t.Errorf("APRS string position without a fix: %s", aprs)
+	}
+	if !strings.Contains(aprs, "/A=/V=4.1/P=1019.5/TI=/TO=5.4/") || !strings.Contains(aprs, "/GPS=/") ||
+		!strings.Contains(aprs, "/ST=67/") {
+		t.Errorf("Invalid values in APRS string: %s", aprs)
+	}
+	// the CSV keeps the columns of the position
+	csv := telem.CsvString()
+	if f := strings.Split(csv, ","); len(f) != 21 || f[2] != "" || f[5] != "" || f[6] != "" || f[8] != "" || f[20] != "67" {
+		t.Errorf("Invalid values in CSV: %s", csv)
+	}
+	if ukhas := telem.UkhasString(); !strings.HasPrefix(ukhas, "$$TEST,1,") || !strings.Contains(ukhas, ",,,,8,4.12,,5.4,") {
+		t.Errorf("Invalid values in UKHAS sentence: %s", ukhas)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(telem.JsonString()), &record); err != nil {
+		t.Fatalf("Bad JSON line: %v", err)
+	}
+	if record["lat"] != nil || record["alt"] != nil || record["tin"] != nil || record["tout"] != 5.4 || record["status"] != 67.0 {
+		t.Errorf("Invalid values in JSON: %v", record)
+	}
+	if p := telem.AprsPosition(); !strings.HasPrefix(p, ">No GPS fix S1 B0 U") {
+		t.Errorf("APRS position report without position: %s", p)
+	}
+	if tlm := telem.AprsTelemetry(); tlm != "T#001,206,243,000,211,008,01000000" {
+		t.Errorf("Invalid values in APRS telemetry: %s", tlm)
+	}
+
+	// stale position, sent as an old fix
+	telem.Update(pos, 90.0, 5.0, 8, "OK", 4.12, 1019.5, 15.5, 5.4, false)
+	telem.SetValidity(Stale, "pos", "gps")
+	if aprs := telem.AprsString(); !strings.Contains(aprs, "/GPS=43.549067N,005.663050W/") || !strings.Contains(aprs, "/ST=65536/") {
+		t.Errorf("Stale values in APRS string: %s", aprs)
+	}
+	if p := telem.AprsPosition(); p[13] != byte(aprsCompOldFix+33) {
+		t.Errorf("Stale APRS position not an old fix: %s", p)
+	}
+
+	// new values are valid
+	telem.Update(pos, 90.0, 5.0, 8, "OK", 4.12, 1019.5, 15.5, 5.4, false)
+	if aprs := telem.AprsString(); !strings.Contains(aprs, "/ST=0/") {
+		t.Errorf("Validity after update: %s", aprs)
+	}
+	if s := Status(65536 | 1<<6); s.Validity("pos") != Stale || s.Validity("tin") != Invalid || s.Validity("alt") != Valid {
+		t.Errorf("Bad status validity: %v", s)
+	}
+}